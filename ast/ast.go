@@ -0,0 +1,230 @@
+// Package ast declares the types used to represent the syntax tree of
+// gp-config configuration files. Trees are built by config.Parser.ParseAST
+// and keep comments, so that tools such as formatters, linters and editors
+// may work on the same grammar as the runtime parser.
+package ast
+
+import (
+	"github.com/cbonello/gp-config/scanner"
+)
+
+type (
+	// Node is implemented by all nodes of the tree.
+	Node interface {
+		Pos() scanner.Position // Position of first character of node.
+		End() scanner.Position // Position following node.
+	}
+
+	// Value is implemented by option values: *Literal and *Array.
+	Value interface {
+		Node
+		valueNode()
+	}
+
+	// Comment is a '#' comment.
+	Comment struct {
+		Hash scanner.Position // Position of '#'.
+		Text string           // Comment text, including '#'.
+	}
+
+	// CommentGroup is a sequence of comments with no declaration in
+	// between.
+	CommentGroup struct {
+		List []*Comment
+	}
+
+	// File is the tree of a configuration.
+	File struct {
+		Filename string
+		Options  []*Option     // Options declared before first section.
+		Sections []*Section    // Sections in declaration order.
+		Footer   *CommentGroup // Comments following last declaration, or nil.
+	}
+
+	// Section is a section declaration and its options.
+	Section struct {
+		Doc     *CommentGroup // Comments preceding section, or nil.
+		Lbrack  scanner.Position
+		Name    *Key
+		Rbrack  scanner.Position
+		Comment *Comment // Comment following ']' on same line, or nil.
+		Options []*Option
+	}
+
+	// Key is a section or option name.
+	Key struct {
+		NamePos scanner.Position
+		Name    string // Name without quotes.
+		Raw     string // Name as written in source; quoted or not.
+	}
+
+	// Option is an option declaration.
+	Option struct {
+		Doc     *CommentGroup // Comments preceding option, or nil.
+		Key     *Key
+		Equal   scanner.Position
+		Value   Value
+		Comment *Comment // Comment following value on same line, or nil.
+	}
+
+	// Literal is a scalar value.
+	Literal struct {
+		ValuePos scanner.Position
+		ValueEnd scanner.Position
+		Kind     scanner.Kind // TkBool, TkInt, TkFloat, TkDate, TkString, TkDuration, TkSize or TkSecret.
+		Raw      string       // Literal as written in source.
+		Value    interface{}  // Decoded value; see scanner.Token.
+	}
+
+	// Array is an array value.
+	Array struct {
+		Lbrack   scanner.Position
+		Elements []*Literal
+		Rbrack   scanner.Position
+		Comments []*Comment // Comments between brackets of multi-line arrays.
+	}
+)
+
+// Pos returns the position of '#'.
+func (c *Comment) Pos() scanner.Position { return c.Hash }
+
+// End returns the position following the comment.
+func (c *Comment) End() scanner.Position {
+	return scanner.Position{Line: c.Hash.Line, Column: c.Hash.Column + len(c.Text)}
+}
+
+// Pos returns the position of first comment.
+func (g *CommentGroup) Pos() scanner.Position { return g.List[0].Pos() }
+
+// End returns the position following last comment.
+func (g *CommentGroup) End() scanner.Position { return g.List[len(g.List)-1].End() }
+
+// Pos returns the position of first declaration of file.
+func (f *File) Pos() scanner.Position {
+	if len(f.Options) > 0 {
+		return f.Options[0].Pos()
+	}
+	if len(f.Sections) > 0 {
+		return f.Sections[0].Pos()
+	}
+	return scanner.Position{Line: 1, Column: 1}
+}
+
+// End returns the position following last declaration of file.
+func (f *File) End() scanner.Position {
+	if len(f.Sections) > 0 {
+		return f.Sections[len(f.Sections)-1].End()
+	}
+	if len(f.Options) > 0 {
+		return f.Options[len(f.Options)-1].End()
+	}
+	return scanner.Position{Line: 1, Column: 1}
+}
+
+// Pos returns the position of '['.
+func (s *Section) Pos() scanner.Position { return s.Lbrack }
+
+// End returns the position following last option of section.
+func (s *Section) End() scanner.Position {
+	if len(s.Options) > 0 {
+		return s.Options[len(s.Options)-1].End()
+	}
+	return scanner.Position{Line: s.Rbrack.Line, Column: s.Rbrack.Column + 1}
+}
+
+// Pos returns the position of first character of name.
+func (k *Key) Pos() scanner.Position { return k.NamePos }
+
+// End returns the position following name.
+func (k *Key) End() scanner.Position {
+	return scanner.Position{Line: k.NamePos.Line, Column: k.NamePos.Column + len(k.Raw)}
+}
+
+// Pos returns the position of option name.
+func (o *Option) Pos() scanner.Position { return o.Key.Pos() }
+
+// End returns the position following option value.
+func (o *Option) End() scanner.Position { return o.Value.End() }
+
+// Pos returns the position of first character of literal.
+func (l *Literal) Pos() scanner.Position { return l.ValuePos }
+
+// End returns the position following literal.
+func (l *Literal) End() scanner.Position { return l.ValueEnd }
+
+// Pos returns the position of '['.
+func (a *Array) Pos() scanner.Position { return a.Lbrack }
+
+// End returns the position following ']'.
+func (a *Array) End() scanner.Position {
+	return scanner.Position{Line: a.Rbrack.Line, Column: a.Rbrack.Column + 1}
+}
+
+func (*Literal) valueNode() {}
+func (*Array) valueNode()   {}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f
+// for each node. If f returns false, children of node are not visited. Doc
+// comments are visited before the node they document, line comments after.
+func Inspect(node Node, f func(Node) bool) {
+	switch n := node.(type) {
+	case *File:
+		if f(n) == false {
+			return
+		}
+		for _, o := range n.Options {
+			Inspect(o, f)
+		}
+		for _, s := range n.Sections {
+			Inspect(s, f)
+		}
+		if n.Footer != nil {
+			Inspect(n.Footer, f)
+		}
+	case *Section:
+		if n.Doc != nil {
+			Inspect(n.Doc, f)
+		}
+		if f(n) == false {
+			return
+		}
+		Inspect(n.Name, f)
+		if n.Comment != nil {
+			f(n.Comment)
+		}
+		for _, o := range n.Options {
+			Inspect(o, f)
+		}
+	case *Option:
+		if n.Doc != nil {
+			Inspect(n.Doc, f)
+		}
+		if f(n) == false {
+			return
+		}
+		Inspect(n.Key, f)
+		Inspect(n.Value, f)
+		if n.Comment != nil {
+			f(n.Comment)
+		}
+	case *Array:
+		if f(n) == false {
+			return
+		}
+		for _, e := range n.Elements {
+			f(e)
+		}
+		for _, c := range n.Comments {
+			f(c)
+		}
+	case *CommentGroup:
+		if f(n) == false {
+			return
+		}
+		for _, c := range n.List {
+			f(c)
+		}
+	default:
+		f(n)
+	}
+}
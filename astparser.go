@@ -0,0 +1,189 @@
+package config
+
+import (
+	"github.com/cbonello/gp-config/ast"
+	"github.com/cbonello/gp-config/scanner"
+)
+
+// ParseAST parses the configuration and returns its syntax tree, comments
+// included. Syntax is checked as by Parse but values are not; arrays mixing
+// types for instance are accepted. Tree literals hold the values returned by
+// package scanner.
+func (p *Parser) ParseAST() (*ast.File, *ConfigurationError) {
+	if p == nil {
+		return nil, nil
+	}
+	p.lexer.Mode |= scanner.ScanComments
+	f := &ast.File{Filename: p.lexer.Filename}
+	err := p.parseFile(f)
+	// Input may look truncated after a read error.
+	if rerr := p.inputError(); rerr != nil {
+		return nil, rerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *Parser) parseFile(f *ast.File) *ConfigurationError {
+	if p.next(); p.lexer.Token.Kind == TkError {
+		return p.newError(&p.lexer.Token, "%s", p.lexer.Token.Value)
+	}
+	for p.skipEmptyLines(); p.lexer.Token.Kind != TkEOF; p.skipEmptyLines() {
+		if p.lexer.Token.Kind == TkLBracket {
+			s, err := p.parseSectionNode()
+			if err != nil {
+				return err
+			}
+			f.Sections = append(f.Sections, s)
+		} else if p.isKey() && len(f.Sections) == 0 {
+			o, err := p.parseOptionNode("")
+			if err != nil {
+				return err
+			}
+			f.Options = append(f.Options, o)
+		} else {
+			return p.expectedError()
+		}
+	}
+	f.Footer = p.commentGroup()
+	return nil
+}
+
+func (p *Parser) parseSectionNode() (*ast.Section, *ConfigurationError) {
+	s := &ast.Section{
+		Doc:    p.commentGroup(),
+		Lbrack: p.lexer.Token.Pos(),
+	}
+	if p.next(); p.isKey() == false {
+		return nil, p.unexpectedError()
+	}
+	s.Name = p.key()
+	// Remember section name for error reporting.
+	header := p.lexer.Token
+	if p.next(); p.lexer.Token.Kind != TkRBracket {
+		return nil, p.unexpectedError()
+	}
+	s.Rbrack = p.lexer.Token.Pos()
+	header.Column, header.EndColumn = p.lexer.Token.Column, p.lexer.Token.EndColumn
+	if p.next(); p.lexer.Token.Kind != TkEOL {
+		return nil, p.unexpectedError()
+	}
+	s.Comment = p.lineComment(s.Rbrack.Line)
+	p.skipEmptyLines()
+	// No options were declared in section?
+	if p.isKey() == false && p.lexer.Token.Kind != TkError {
+		return nil, p.emptySectionError(p.lexer.Filename, &header)
+	}
+	section := p.formatOptionName("", s.Name.Name)
+	for p.isKey() {
+		o, err := p.parseOptionNode(section)
+		if err != nil {
+			return nil, err
+		}
+		s.Options = append(s.Options, o)
+	}
+	return s, nil
+}
+
+func (p *Parser) parseOptionNode(section string) (*ast.Option, *ConfigurationError) {
+	o := &ast.Option{
+		Doc: p.commentGroup(),
+		Key: p.key(),
+	}
+	option := p.formatOptionName(section, o.Key.Name)
+	if p.next(); p.lexer.Token.Kind != TkEqual {
+		return nil, p.unexpectedError()
+	}
+	o.Equal = p.lexer.Token.Pos()
+	var array *ast.Array
+	if p.next(); p.lexer.Token.Kind == TkLBracket {
+		var err *ConfigurationError
+		if array, err = p.parseArrayNode(option); err != nil {
+			return nil, err
+		}
+		o.Value = array
+	} else {
+		if err := p.parseValue(nil, option); err != nil {
+			return nil, err
+		}
+		o.Value = p.literal()
+		p.next()
+	}
+	if k := p.lexer.Token.Kind; k != TkEOL && k != TkEOF {
+		return nil, p.unexpectedError()
+	}
+	o.Comment = p.lineComment(o.Value.End().Line)
+	if array != nil {
+		// Other comments were read between brackets.
+		if g := p.commentGroup(); g != nil {
+			array.Comments = g.List
+		}
+	}
+	p.skipEmptyLines()
+	return o, nil
+}
+
+func (p *Parser) parseArrayNode(option string) (*ast.Array, *ConfigurationError) {
+	a := &ast.Array{
+		Lbrack: p.lexer.Token.Pos(),
+	}
+	p.next()
+	p.skipEmptyLines()
+	for {
+		if err := p.parseValue(nil, option); err != nil {
+			return nil, err
+		}
+		a.Elements = append(a.Elements, p.literal())
+		p.next()
+		p.skipEmptyLines()
+		if p.lexer.Token.Kind == TkComma {
+			p.next()
+		} else if p.lexer.Token.Kind == TkRBracket {
+			a.Rbrack = p.lexer.Token.Pos()
+			p.next()
+			return a, nil
+		}
+		p.skipEmptyLines()
+	}
+}
+
+func (p *Parser) key() *ast.Key {
+	return &ast.Key{
+		NamePos: p.lexer.Token.Pos(),
+		Name:    p.lexer.Token.Value.(string),
+		Raw:     p.lexer.Token.Raw,
+	}
+}
+
+func (p *Parser) literal() *ast.Literal {
+	t := &p.lexer.Token
+	return &ast.Literal{
+		ValuePos: t.Pos(),
+		ValueEnd: t.End(),
+		Kind:     t.Kind,
+		Raw:      t.Raw,
+		Value:    t.Value,
+	}
+}
+
+// commentGroup returns the comments read since last node, or nil.
+func (p *Parser) commentGroup() *ast.CommentGroup {
+	if len(p.comments) == 0 {
+		return nil
+	}
+	g := &ast.CommentGroup{List: p.comments}
+	p.comments = nil
+	return g
+}
+
+// lineComment returns the comment ending given line, if any.
+func (p *Parser) lineComment(line int) *ast.Comment {
+	if n := len(p.comments); n > 0 && p.comments[n-1].Hash.Line == line {
+		c := p.comments[n-1]
+		p.comments = p.comments[:n-1]
+		return c
+	}
+	return nil
+}
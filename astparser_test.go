@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"github.com/cbonello/gp-config"
+	"github.com/cbonello/gp-config/ast"
+	"github.com/cbonello/gp-config/scanner"
+	. "launchpad.net/gocheck"
+	"time"
+)
+
+type (
+	ASTTests struct{}
+)
+
+var (
+	_ = Suite(&ASTTests{})
+)
+
+// ParseAST(): options, sections, arrays and keys.
+func (at *ASTTests) TestParseAST1(c *C) {
+	contents := `version = [1, 0,
+	12]
+["example.com"]
+	timeout = 1m30s
+	"max size" = 10MiB
+[database]
+	created = 1979-05-27T07:32:00Z`
+
+	f, err := config.NewStringParser(contents).ParseAST()
+	c.Assert(err, IsNil)
+	c.Check(f.Filename, Equals, ":string:")
+	c.Assert(f.Options, HasLen, 1)
+	c.Check(f.Options[0].Key.Name, Equals, "version")
+	a, ok := f.Options[0].Value.(*ast.Array)
+	c.Assert(ok, Equals, true)
+	c.Assert(a.Elements, HasLen, 3)
+	c.Check(a.Elements[2].Value, Equals, int64(12))
+	c.Check(a.Pos(), Equals, scanner.Position{Line: 1, Column: 11})
+	c.Check(a.End(), Equals, scanner.Position{Line: 2, Column: 5})
+
+	c.Assert(f.Sections, HasLen, 2)
+	s := f.Sections[0]
+	c.Check(s.Name.Name, Equals, "example.com")
+	c.Check(s.Name.Raw, Equals, `"example.com"`)
+	c.Assert(s.Options, HasLen, 2)
+	l := s.Options[0].Value.(*ast.Literal)
+	c.Check(l.Kind, Equals, scanner.TkDuration)
+	c.Check(l.Raw, Equals, "1m30s")
+	c.Check(l.Value, Equals, 90*time.Second)
+	c.Check(s.Options[1].Key.Name, Equals, "max size")
+	l = s.Options[1].Value.(*ast.Literal)
+	c.Check(l.Kind, Equals, scanner.TkSize)
+	c.Check(l.Value, Equals, int64(10<<20))
+	c.Check(s.Pos(), Equals, scanner.Position{Line: 3, Column: 1})
+	c.Check(s.End(), Equals, scanner.Position{Line: 5, Column: 20})
+
+	s = f.Sections[1]
+	c.Check(s.Name.Name, Equals, "database")
+	c.Check(s.Options[0].Value.(*ast.Literal).Kind, Equals, scanner.TkDate)
+}
+
+// ParseAST(): comments.
+func (at *ASTTests) TestParseAST2(c *C) {
+	contents := `# Header
+
+# Version.
+version = 1 # Major
+[server] # Web server
+	# Port
+	port = 80
+	hosts = [
+		"a", # First
+		"b"
+	] # Hosts
+
+# Footer`
+
+	f, err := config.NewStringParser(contents).ParseAST()
+	c.Assert(err, IsNil)
+	o := f.Options[0]
+	c.Assert(o.Doc, NotNil)
+	c.Assert(o.Doc.List, HasLen, 2)
+	c.Check(o.Doc.List[0].Text, Equals, "# Header")
+	c.Check(o.Doc.List[1].Text, Equals, "# Version.")
+	c.Check(o.Doc.List[1].Hash, Equals, scanner.Position{Line: 3, Column: 1})
+	c.Check(o.Comment.Text, Equals, "# Major")
+
+	s := f.Sections[0]
+	c.Check(s.Doc, IsNil)
+	c.Check(s.Comment.Text, Equals, "# Web server")
+	c.Check(s.Options[0].Doc.List[0].Text, Equals, "# Port")
+	c.Check(s.Options[0].Comment, IsNil)
+	a := s.Options[1].Value.(*ast.Array)
+	c.Assert(a.Comments, HasLen, 1)
+	c.Check(a.Comments[0].Text, Equals, "# First")
+	c.Check(s.Options[1].Comment.Text, Equals, "# Hosts")
+
+	c.Assert(f.Footer, NotNil)
+	c.Check(f.Footer.List[0].Text, Equals, "# Footer")
+	c.Check(f.Footer.Pos(), Equals, scanner.Position{Line: 13, Column: 1})
+}
+
+// ParseAST(): files with comments only.
+func (at *ASTTests) TestParseAST3(c *C) {
+	f, err := config.NewStringParser("\n# Nothing yet.\n\n").ParseAST()
+	c.Assert(err, IsNil)
+	c.Check(f.Options, HasLen, 0)
+	c.Check(f.Sections, HasLen, 0)
+	c.Check(f.Footer.List, HasLen, 1)
+
+	// Runtime parser agrees.
+	cfg := config.NewConfiguration()
+	c.Check(cfg.LoadString("\n# Nothing yet.\n\n"), IsNil)
+}
+
+// ParseAST(): syntax errors are reported as by Parse.
+func (at *ASTTests) TestParseAST4(c *C) {
+	tests := []struct {
+		contents string
+		err      string
+	}{
+		{"a = ", "unexpected end-of-file"},
+		{"[a]\n[b]\nc = 1", "empty section a"},
+		{"[a]\nb = 1 2", "unexpected integer 2"},
+		{"[a]\nb = 'c'", "'a.b': unexpected ''' character"},
+		{"= 1", "expected section or option declaration"},
+	}
+
+	for _, t := range tests {
+		f, err := config.NewStringParser(t.contents).ParseAST()
+		c.Check(f, IsNil)
+		c.Check(err, ErrorMatches, t.err)
+
+		cfg := config.NewConfiguration()
+		c.Check(cfg.LoadString(t.contents), ErrorMatches, t.err)
+	}
+}
+
+// Inspect().
+func (at *ASTTests) TestInspect1(c *C) {
+	contents := `# Doc
+a = [1, 2] # Line
+[s]
+	b = "x"`
+
+	f, err := config.NewStringParser(contents).ParseAST()
+	c.Assert(err, IsNil)
+	nodes := []string{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Comment:
+			nodes = append(nodes, n.Text)
+		case *ast.Key:
+			nodes = append(nodes, n.Name)
+		case *ast.Literal:
+			nodes = append(nodes, n.Raw)
+		case *ast.Section:
+			// Skips options of section.
+			nodes = append(nodes, "section")
+			return false
+		}
+		return true
+	})
+	c.Check(nodes, EqualSlice, []string{"# Doc", "a", "1", "2", "# Line", "section"})
+}
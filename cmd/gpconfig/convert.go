@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"strings"
+)
+
+// runConvert implements "gpconfig convert". Input files are read in the
+// format registered for their extension.
+func (a *app) runConvert(args []string) int {
+	var files fileList
+	var from, to, prefix string
+	formats := strings.Join(config.Formats(), ", ")
+	flags := a.flagSet("convert")
+	flags.Var(&files, "f", "configuration file; may be repeated, later files override earlier ones")
+	flags.StringVar(&from, "from", config.NativeFormat, "format of standard input: "+formats)
+	flags.StringVar(&to, "to", "json", "output format: "+formats)
+	flags.StringVar(&prefix, "prefix", "", "prefix of variable names in env format")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	for _, name := range []string{from, to} {
+		if config.LookupFormat(name) == nil {
+			fmt.Fprintf(a.stderr, "gpconfig: unknown format %q\n", name)
+			return exitUsage
+		}
+	}
+
+	cfg := newConfiguration()
+	var err error
+	if len(files) == 0 {
+		err = cfg.LoadFormat(from, "<standard input>", a.stdin)
+	} else {
+		cfg, err = a.load(files)
+	}
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	if to == "env" {
+		err = cfg.ExportEnv(a.stdout, prefix)
+	} else {
+		err = cfg.Export(a.stdout, to)
+	}
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+type (
+	ConvertTests struct{}
+)
+
+var (
+	_ = Suite(&ConvertTests{})
+)
+
+// gpconfig convert.
+func (ct *ConvertTests) TestConvert1(c *C) {
+	dir := c.MkDir()
+	fn1 := writeFile(c, dir, "a.cfg", "[server]\n\thost = \"localhost\"\n\tport = 80")
+	fn2 := writeFile(c, dir, "b.cfg", "[server]\n\tport = 8080\n\tratio = 1.0")
+
+	code, stdout, _ := runApp("", "convert", "-f", fn1, "-f", fn2)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, `{
+  "server": {
+    "host": "localhost",
+    "port": 8080,
+    "ratio": 1.0
+  }
+}
+`)
+
+	code, stdout, _ = runApp("", "convert", "-f", fn1, "-to", "yaml")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "server:\n  host: \"localhost\"\n  port: 80\n")
+
+	code, stdout, _ = runApp("a = 1", "convert", "-to", "env", "-prefix", "app")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "APP_A=1\n")
+
+	// Formats are chosen by extension, or with -from for standard input.
+	fn3 := writeFile(c, dir, "c.json", `{"server": {"port": 8081}}`)
+	code, stdout, _ = runApp("", "convert", "-f", fn1, "-f", fn3, "-to", "ini")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "[server]\nhost = \"localhost\"\nport = 8081\n")
+
+	code, stdout, _ = runApp("[server]\nport = 80", "convert", "-from", "ini", "-to", "cfg")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "[server]\n\tport = 80\n")
+}
+
+// gpconfig convert: errors.
+func (ct *ConvertTests) TestConvert2(c *C) {
+	code, _, stderr := runApp("a = 1", "convert", "-to", "xml")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Equals, "gpconfig: unknown format \"xml\"\n")
+
+	code, _, stderr = runApp("a = 1", "convert", "-from", "yaml")
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Equals, "gpconfig: yaml format cannot be read\n")
+
+	code, _, stderr = runApp("a = 1\n[A]\n\tb = 1", "convert")
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Equals, "gpconfig: 'a': option and section share the same name\n")
+
+	code, _, _ = runApp("a = ", "convert")
+	c.Check(code, Equals, exitError)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"io"
+	"strings"
+)
+
+// Environment variable giving the key of encrypted values, in base64, when
+// -key-file is not given.
+const keyEnv = "GPCONFIG_KEY"
+
+// keyProvider returns the provider of the key stored in given file, or in
+// environment variable GPCONFIG_KEY if filename is empty.
+func keyProvider(filename string) config.KeyProvider {
+	if filename != "" {
+		return config.KeyFromFile(filename)
+	}
+	return config.KeyFromEnv(keyEnv)
+}
+
+// runEncrypt implements "gpconfig encrypt".
+func (a *app) runEncrypt(args []string) int {
+	var keyFile string
+	var genKey bool
+	flags := a.flagSet("encrypt")
+	flags.StringVar(&keyFile, "key-file", "", "file holding the key in base64 (default $"+keyEnv+")")
+	flags.BoolVar(&genKey, "genkey", false, "print a new random key in base64")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if genKey {
+		if flags.NArg() != 0 {
+			flags.Usage()
+			return exitUsage
+		}
+		key, err := config.GenerateKey()
+		if err != nil {
+			a.report(err)
+			return exitError
+		}
+		fmt.Fprintln(a.stdout, config.FormatKey(key))
+		return exitOK
+	}
+	return a.crypt(flags.Args(), keyFile, config.Encrypt)
+}
+
+// runDecrypt implements "gpconfig decrypt".
+func (a *app) runDecrypt(args []string) int {
+	var keyFile string
+	flags := a.flagSet("decrypt")
+	flags.StringVar(&keyFile, "key-file", "", "file holding the key in base64 (default $"+keyEnv+")")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	return a.crypt(flags.Args(), keyFile, config.Decrypt)
+}
+
+// crypt prints the result of fn applied to the value given as argument, or
+// read from standard input without trailing newline.
+func (a *app) crypt(args []string, keyFile string, fn func(string, []byte) (string, error)) int {
+	var value string
+	switch len(args) {
+	case 0:
+		b, err := io.ReadAll(a.stdin)
+		if err != nil {
+			a.report(err)
+			return exitError
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+	case 1:
+		value = args[0]
+	default:
+		fmt.Fprintf(a.stderr, "gpconfig: too many arguments\n")
+		return exitUsage
+	}
+	key, err := keyProvider(keyFile).Key()
+	if err != nil {
+		a.report(fmt.Errorf("cannot get key: %s", err))
+		return exitError
+	}
+	result, err := fn(value, key)
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	fmt.Fprintln(a.stdout, result)
+	return exitOK
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+	"os"
+	"strings"
+)
+
+type (
+	CryptTests struct{}
+)
+
+var (
+	_ = Suite(&CryptTests{})
+)
+
+// gpconfig encrypt, gpconfig decrypt.
+func (ct *CryptTests) TestCrypt1(c *C) {
+	code, key, _ := runApp("", "encrypt", "-genkey")
+	c.Assert(code, Equals, exitOK)
+	keyFile := writeFile(c, c.MkDir(), "app.key", key)
+
+	code, encrypted, _ := runApp("", "encrypt", "-key-file", keyFile, "s3cr3t")
+	c.Assert(code, Equals, exitOK)
+	c.Check(strings.HasPrefix(encrypted, "enc:v1:"), Equals, true)
+	code, stdout, _ := runApp(encrypted, "decrypt", "-key-file", keyFile)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "s3cr3t\n")
+
+	// Key given by environment variable; values are not decrypted by get.
+	os.Setenv(keyEnv, key)
+	defer os.Unsetenv(keyEnv)
+	code, encrypted, _ = runApp("s3cr3t\n", "encrypt")
+	c.Assert(code, Equals, exitOK)
+	code, stdout, _ = runApp("password = \""+strings.TrimSpace(encrypted)+"\"", "get", "password")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, encrypted)
+}
+
+// gpconfig decrypt: errors.
+func (ct *CryptTests) TestCrypt2(c *C) {
+	os.Unsetenv(keyEnv)
+	code, _, stderr := runApp("", "decrypt", "enc:v1:AAAA")
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Equals, "gpconfig: cannot get key: environment variable GPCONFIG_KEY is not set\n")
+
+	dir := c.MkDir()
+	key1 := writeFile(c, dir, "1.key", "MDEyMzQ1Njc4OWFiY2RlZg==\n")
+	key2 := writeFile(c, dir, "2.key", "ZmVkY2JhOTg3NjU0MzIxMA==\n")
+	_, encrypted, _ := runApp("", "encrypt", "-key-file", key1, "s3cr3t")
+	code, _, stderr = runApp(encrypted, "decrypt", "-key-file", key2)
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Equals, "gpconfig: cannot decrypt value; wrong key or corrupted value\n")
+
+	code, _, _ = runApp("", "encrypt", "-genkey", "s3cr3t")
+	c.Check(code, Equals, exitUsage)
+}
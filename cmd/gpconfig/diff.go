@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cbonello/gp-config"
+)
+
+// Exit code of "gpconfig diff" if configurations differ. Like diff(1), the
+// command exits with exitUsage on errors.
+const exitDifferent = 1
+
+// runDiff implements "gpconfig diff". Configurations are given as layered
+// file sets (-a and -b), or as two files.
+func (a *app) runDiff(args []string) int {
+	var aFiles, bFiles fileList
+	var asJSON bool
+	flags := a.flagSet("diff")
+	flags.Var(&aFiles, "a", "file of first configuration; may be repeated, later files override earlier ones")
+	flags.Var(&bFiles, "b", "file of second configuration; may be repeated, later files override earlier ones")
+	flags.BoolVar(&asJSON, "json", false, "print changes in JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	switch {
+	case len(aFiles) == 0 && len(bFiles) == 0 && flags.NArg() == 2:
+		aFiles, bFiles = fileList{flags.Arg(0)}, fileList{flags.Arg(1)}
+	case len(aFiles) == 0 || len(bFiles) == 0 || flags.NArg() != 0:
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfgA, err := a.load(aFiles)
+	if err != nil {
+		a.report(err)
+		return exitUsage
+	}
+	cfgB, err := a.load(bFiles)
+	if err != nil {
+		a.report(err)
+		return exitUsage
+	}
+
+	changes := config.Diff(cfgA, cfgB)
+	if asJSON {
+		type change struct {
+			Kind   string      `json:"kind"`
+			Option string      `json:"option"`
+			Old    interface{} `json:"old,omitempty"`
+			New    interface{} `json:"new,omitempty"`
+		}
+		list := make([]change, len(changes))
+		for i, c := range changes {
+			list[i] = change{c.Kind.String(), c.Option, nil, nil}
+			if c.Old != nil {
+				list[i].Old = config.JSONValue(c.Old)
+			}
+			if c.New != nil {
+				list[i].New = config.JSONValue(c.New)
+			}
+		}
+		if code := a.printJSON(list); code != exitOK {
+			return exitUsage
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Fprintln(a.stdout, c)
+		}
+	}
+	if len(changes) > 0 {
+		return exitDifferent
+	}
+	return exitOK
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+type (
+	DiffTests struct{}
+)
+
+var (
+	_ = Suite(&DiffTests{})
+)
+
+// gpconfig diff: two files.
+func (dt *DiffTests) TestDiff1(c *C) {
+	dir := c.MkDir()
+	fn1 := writeFile(c, dir, "a.cfg", defaults)
+	fn2 := writeFile(c, dir, "b.cfg", defaults+"\tdebug = true\n")
+	fn3 := writeFile(c, dir, "c.cfg", "# Same options.\n"+defaults)
+
+	code, stdout, _ := runApp("", "diff", fn1, fn2)
+	c.Check(code, Equals, exitDifferent)
+	c.Check(stdout, Equals, "+ \"example.com\".debug = true\n")
+
+	code, stdout, _ = runApp("", "diff", fn1, fn3)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "")
+
+	code, _, stderr := runApp("", "diff", fn1, writeFile(c, dir, "d.cfg", "a = "))
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Matches, "(?s).*d.cfg:1:5: unexpected end-of-file\n.*")
+
+	code, _, _ = runApp("", "diff", fn1)
+	c.Check(code, Equals, exitUsage)
+}
+
+// gpconfig diff: layered file sets.
+func (dt *DiffTests) TestDiff2(c *C) {
+	dir := c.MkDir()
+	fn1 := writeFile(c, dir, "defaults.cfg", defaults)
+	fn2 := writeFile(c, dir, "prod.cfg", "[server]\n\tport = 443\n")
+	fn3 := writeFile(c, dir, "debug.cfg", overrides+"\tdebug = true\n")
+
+	code, stdout, _ := runApp("", "diff", "-a", fn1, "-a", fn2, "-b", fn1, "-b", fn3)
+	c.Check(code, Equals, exitDifferent)
+	c.Check(stdout, Equals, `+ server.debug = true
+~ server.port = 443 -> 8080
++ server.ratio = inf
+`)
+
+	code, stdout, _ = runApp("", "diff", "-json", "-a", fn1, "-a", fn2, "-b", fn1)
+	c.Check(code, Equals, exitDifferent)
+	c.Check(stdout, Equals, `[
+  {
+    "kind": "changed",
+    "option": "server.port",
+    "old": 443,
+    "new": 80
+  }
+]
+`)
+
+	code, _, _ = runApp("", "diff", "-a", fn1, fn2)
+	c.Check(code, Equals, exitUsage)
+}
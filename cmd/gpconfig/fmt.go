@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/cbonello/gp-config/format"
+	"github.com/cbonello/gp-config/internal/textdiff"
+	"io"
+	"os"
+)
+
+type fmtOptions struct {
+	list  bool // List files whose formatting differs.
+	diff  bool // Display diffs.
+	write bool // Write result to source files.
+}
+
+// runFmt implements "gpconfig fmt". Like gofmt, it formats standard input if
+// no path is given and configuration files (*.cfg) found in directories.
+func (a *app) runFmt(args []string) int {
+	opts := fmtOptions{}
+	flags := a.flagSet("fmt")
+	flags.BoolVar(&opts.list, "l", false, "list files whose formatting differs from gpconfig fmt's")
+	flags.BoolVar(&opts.diff, "d", false, "display diffs instead of rewriting files")
+	flags.BoolVar(&opts.write, "w", false, "write result to (source) file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if opts.write {
+			fmt.Fprintln(a.stderr, "gpconfig: cannot use -w with standard input")
+			return exitUsage
+		}
+		if err := a.formatFile("<standard input>", a.stdin, opts); err != nil {
+			a.report(err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	status := exitOK
+	for _, path := range flags.Args() {
+		err := walkConfigs(path, func(filename string, r io.Reader) {
+			if err := a.formatFile(filename, r, opts); err != nil {
+				a.report(err)
+				status = exitError
+			}
+		})
+		if err != nil {
+			a.report(err)
+			status = exitError
+		}
+	}
+	return status
+}
+
+// formatFile formats the configuration read from r according to opts.
+func (a *app) formatFile(filename string, r io.Reader, opts fmtOptions) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	res, err := format.Source(filename, src)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(src, res) == false {
+		if opts.list {
+			fmt.Fprintln(a.stdout, filename)
+		}
+		if opts.write {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filename, res, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		if opts.diff {
+			a.stdout.Write(textdiff.Unified(filename+".orig", filename, src, res))
+		}
+	}
+	if opts.list == false && opts.write == false && opts.diff == false {
+		a.stdout.Write(res)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+	"path/filepath"
+)
+
+type (
+	FmtTests struct{}
+)
+
+var (
+	_ = Suite(&FmtTests{})
+)
+
+const (
+	unformatted = "[server]\nport=80\n"
+	formatted   = "[server]\n\tport = 80\n"
+)
+
+// gpconfig fmt: standard input.
+func (ft *FmtTests) TestFmt1(c *C) {
+	code, stdout, _ := runApp(unformatted, "fmt")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, formatted)
+
+	code, _, stderr := runApp("[server]\nport='80'", "fmt")
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Matches, "(?s)<standard input>:2:6: 'server.port': unexpected ''' character\n.*")
+
+	code, _, _ = runApp(unformatted, "fmt", "-w")
+	c.Check(code, Equals, exitUsage)
+}
+
+// gpconfig fmt -l: files and directories.
+func (ft *FmtTests) TestFmt2(c *C) {
+	dir := c.MkDir()
+	fn1 := writeFile(c, dir, "a.cfg", unformatted)
+	writeFile(c, dir, "b.cfg", formatted)
+	writeFile(c, dir, "c.txt", unformatted)
+
+	code, stdout, _ := runApp("", "fmt", "-l", dir)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, fn1+"\n")
+	c.Check(readFile(c, fn1), Equals, unformatted)
+
+	// Files given on command line are formatted whatever their extension.
+	fn2 := filepath.Join(dir, "c.txt")
+	code, stdout, _ = runApp("", "fmt", "-l", fn2)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, fn2+"\n")
+
+	code, _, stderr := runApp("", "fmt", filepath.Join(dir, "d.cfg"))
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Matches, "gpconfig: .*d.cfg: no such file or directory\n")
+}
+
+// gpconfig fmt -d.
+func (ft *FmtTests) TestFmt3(c *C) {
+	fn := writeFile(c, c.MkDir(), "a.cfg", unformatted)
+	code, stdout, _ := runApp("", "fmt", "-d", fn)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "--- "+fn+".orig\n+++ "+fn+"\n@@ -1,2 +1,2 @@\n [server]\n-port=80\n+\tport = 80\n")
+	c.Check(readFile(c, fn), Equals, unformatted)
+}
+
+// gpconfig fmt -w.
+func (ft *FmtTests) TestFmt4(c *C) {
+	fn := writeFile(c, c.MkDir(), "a.cfg", unformatted)
+	code, stdout, _ := runApp("", "fmt", "-w", "-l", fn)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, fn+"\n")
+	c.Check(readFile(c, fn), Equals, formatted)
+
+	// Idempotent.
+	code, stdout, _ = runApp("", "fmt", "-l", fn)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// fileList is a flag that may be repeated to give several files.
+type fileList []string
+
+func (l *fileList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *fileList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// newConfiguration returns a configuration whose secrets are not resolved
+// nor encrypted values decrypted: commands do not use their values, and files
+// holding them must remain readable without credentials.
+func newConfiguration() *config.Configuration {
+	cfg := config.NewConfiguration()
+	cfg.SetResolveSecrets(false)
+	return cfg
+}
+
+// load loads given files in order, later ones overriding options of earlier
+// ones, or standard input if no file is given.
+func (a *app) load(files []string) (*config.Configuration, error) {
+	cfg := newConfiguration()
+	if len(files) == 0 {
+		if err := cfg.LoadReader("<standard input>", a.stdin); err != nil {
+			return nil, err
+		}
+	}
+	for _, fn := range files {
+		if err := cfg.LoadFile(fn); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// runGet implements "gpconfig get". It exits with exitNotFound if the option
+// is not defined.
+func (a *app) runGet(args []string) int {
+	var files fileList
+	var asJSON bool
+	flags := a.flagSet("get")
+	flags.Var(&files, "f", "configuration file; may be repeated, later files override earlier ones")
+	flags.BoolVar(&asJSON, "json", false, "print value in JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfg, err := a.load(files)
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	value, err := cfg.Get(flags.Arg(0))
+	if err != nil {
+		a.report(err)
+		if errors.Is(err, config.ErrUnknownOption) {
+			return exitNotFound
+		}
+		return exitError
+	}
+	if asJSON {
+		return a.printJSON(config.JSONValue(value))
+	}
+	fmt.Fprintln(a.stdout, rawValue(value))
+	return exitOK
+}
+
+// runList implements "gpconfig list". It exits with exitNotFound if given
+// section is not defined.
+func (a *app) runList(args []string) int {
+	var files fileList
+	var asJSON, sections bool
+	flags := a.flagSet("list")
+	flags.Var(&files, "f", "configuration file; may be repeated, later files override earlier ones")
+	flags.BoolVar(&asJSON, "json", false, "print list in JSON")
+	flags.BoolVar(&sections, "sections", false, "list sections instead of options")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 1 || (sections && flags.NArg() > 0) {
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfg, err := a.load(files)
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	names := []string{}
+	switch {
+	case sections:
+		for _, s := range cfg.Sections() {
+			if s != "" {
+				names = append(names, s)
+			}
+		}
+	case flags.NArg() == 1:
+		section := flags.Arg(0)
+		if cfg.IsSection(section) == false {
+			fmt.Fprintf(a.stderr, "gpconfig: '%s': unknown section\n", section)
+			return exitNotFound
+		}
+		names = cfg.Options(section)
+	default:
+		for _, s := range cfg.Sections() {
+			names = append(names, cfg.Options(s)...)
+		}
+	}
+	if asJSON {
+		return a.printJSON(names)
+	}
+	for _, name := range names {
+		fmt.Fprintln(a.stdout, name)
+	}
+	return exitOK
+}
+
+func (a *app) printJSON(v interface{}) int {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		a.report(err)
+		return exitError
+	}
+	return exitOK
+}
+
+// rawValue formats an option value for shell scripts: strings are not
+// quoted and array elements are written one per line.
+func rawValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		// time.Duration and config.Size.
+		return v.String()
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		elements := make([]string, rv.Len())
+		for i := range elements {
+			elements[i] = rawValue(rv.Index(i).Interface())
+		}
+		return strings.Join(elements, "\n")
+	}
+	return fmt.Sprint(v)
+}
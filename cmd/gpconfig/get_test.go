@@ -0,0 +1,146 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+	"strings"
+)
+
+type (
+	GetTests struct{}
+)
+
+var (
+	_ = Suite(&GetTests{})
+)
+
+const (
+	defaults = `version = [1, 0]
+[server]
+	host = "localhost"
+	port = 80
+	timeout = 1m30s
+	max_body = 10MiB
+["example.com"]
+	root = "/var/www"
+`
+	overrides = `[server]
+	port = 8080
+	ratio = inf
+`
+)
+
+// gpconfig get: files are layered.
+func (gt *GetTests) TestGet1(c *C) {
+	dir := c.MkDir()
+	fn1 := writeFile(c, dir, "a.cfg", defaults)
+	fn2 := writeFile(c, dir, "b.cfg", overrides)
+
+	tests := []struct {
+		option   string
+		expected string
+	}{
+		{"server.host", "localhost\n"},
+		{"server.port", "8080\n"},
+		{"server.timeout", "1m30s\n"},
+		{"server.max_body", "10MiB\n"},
+		{"version", "1\n0\n"},
+		{`"example.com".root`, "/var/www\n"},
+	}
+	for _, t := range tests {
+		code, stdout, _ := runApp("", "get", "-f", fn1, "-f", fn2, t.option)
+		c.Check(code, Equals, exitOK)
+		c.Check(stdout, Equals, t.expected)
+	}
+
+	code, stdout, _ := runApp(defaults, "get", "server.port")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "80\n")
+}
+
+// gpconfig get -json.
+func (gt *GetTests) TestGet2(c *C) {
+	tests := []struct {
+		option   string
+		expected string
+	}{
+		{"server.host", "\"localhost\"\n"},
+		{"server.port", "8080\n"},
+		{"server.timeout", "\"1m30s\"\n"},
+		{"server.max_body", "\"10MiB\"\n"},
+		{"server.ratio", "\"inf\"\n"},
+		{"version", "[\n  1,\n  0\n]\n"},
+	}
+	for _, t := range tests {
+		code, stdout, _ := runApp(defaults+overrides, "get", "-json", t.option)
+		c.Check(code, Equals, exitOK)
+		c.Check(stdout, Equals, t.expected)
+	}
+}
+
+// gpconfig get: exit codes.
+func (gt *GetTests) TestGet3(c *C) {
+	code, _, stderr := runApp(defaults, "get", "server.user")
+	c.Check(code, Equals, exitNotFound)
+	c.Check(stderr, Equals, "gpconfig: 'server.user': unknown option\n")
+
+	code, _, stderr = runApp("a = ", "get", "a")
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Matches, "(?s)<standard input>:1:5: unexpected end-of-file\n.*")
+
+	code, _, stderr = runApp("", "get", "-f", "/nonexistent.cfg", "a")
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Matches, "gpconfig: .*nonexistent.cfg.*\n")
+
+	code, _, _ = runApp("", "get")
+	c.Check(code, Equals, exitUsage)
+}
+
+// gpconfig get, set and convert: secrets are not resolved.
+func (gt *GetTests) TestGet4(c *C) {
+	contents := "[server]\n\tport = 80\n\tpassword = secret(\"env:GPCONFIG_TEST_UNSET\")\n"
+	fn := writeFile(c, c.MkDir(), "s.cfg", contents)
+	code, stdout, _ := runApp("", "get", "-f", fn, "server.port")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "80\n")
+	code, stdout, _ = runApp("", "get", "-f", fn, "server.password")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "secret(\"env:GPCONFIG_TEST_UNSET\")\n")
+
+	code, stdout, _ = runApp("", "convert", "-f", fn, "-to", "json")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Matches, `(?s).*"password": "secret\(\\"env:GPCONFIG_TEST_UNSET\\"\)".*`)
+
+	code, _, stderr := runApp("", "set", fn, "server.port", "9090")
+	c.Check(code, Equals, exitOK, Commentf(stderr))
+	c.Check(readFile(c, fn), Equals, strings.Replace(contents, "80", "9090", 1))
+}
+
+// gpconfig list.
+func (gt *GetTests) TestList1(c *C) {
+	code, stdout, _ := runApp(defaults, "list")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "version\n\"example.com\".root\nserver.host\nserver.max_body\nserver.port\nserver.timeout\n")
+
+	code, stdout, _ = runApp(defaults, "list", "server")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "server.host\nserver.max_body\nserver.port\nserver.timeout\n")
+
+	code, stdout, _ = runApp(defaults, "list", "-sections")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "\"example.com\"\nserver\n")
+
+	code, stdout, _ = runApp(defaults, "list", "-json", "-sections")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "[\n  \"\\\"example.com\\\"\",\n  \"server\"\n]\n")
+
+	code, stdout, _ = runApp(defaults, "list", `"example.com"`)
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "\"example.com\".root\n")
+
+	code, _, stderr := runApp(defaults, "list", "client")
+	c.Check(code, Equals, exitNotFound)
+	c.Check(stderr, Equals, "gpconfig: 'client': unknown section\n")
+
+	code, _, _ = runApp("[a", "list")
+	c.Check(code, Equals, exitError)
+}
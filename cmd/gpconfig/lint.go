@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"github.com/cbonello/gp-config/lint"
+	"io"
+	"strings"
+)
+
+// runLint implements "gpconfig lint". It exits with exitError if issues or
+// errors were reported.
+func (a *app) runLint(args []string) int {
+	var enable, disable string
+	var asJSON, list bool
+	flags := a.flagSet("lint")
+	flags.StringVar(&enable, "enable", "", "comma-separated list of rules to apply instead of all rules")
+	flags.StringVar(&disable, "disable", "", "comma-separated list of rules not to apply")
+	flags.BoolVar(&asJSON, "json", false, "report issues in JSON")
+	flags.BoolVar(&list, "rules", false, "list rules and exit")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if list {
+		for _, r := range lint.Rules() {
+			fmt.Fprintf(a.stdout, "%-20s%s\n", r.Name, r.Description)
+		}
+		return exitOK
+	}
+	rules, err := selectRules(enable, disable)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "gpconfig: %s\n", err)
+		return exitUsage
+	}
+
+	// Syntax errors are reported as issues in JSON output.
+	issues := []lint.Issue{}
+	status := exitOK
+	check := func(filename string, r io.Reader) {
+		src, err := io.ReadAll(r)
+		if err == nil {
+			var found []lint.Issue
+			if found, err = lint.Source(filename, src, rules); err == nil {
+				issues = append(issues, found...)
+				if asJSON == false {
+					for _, issue := range found {
+						fmt.Fprintln(a.stdout, issue)
+					}
+				}
+				return
+			}
+		}
+		status = exitError
+		var cerr *config.ConfigurationError
+		if asJSON && errors.As(err, &cerr) {
+			issues = append(issues, lint.Issue{Filename: filename, Line: cerr.Line,
+				Column: cerr.Column, Rule: "syntax", Message: cerr.Error()})
+		} else {
+			a.report(err)
+		}
+	}
+
+	if flags.NArg() == 0 {
+		check("<standard input>", a.stdin)
+	}
+	for _, path := range flags.Args() {
+		if err := walkConfigs(path, check); err != nil {
+			a.report(err)
+			status = exitError
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(issues)
+	}
+	if len(issues) > 0 {
+		status = exitError
+	}
+	return status
+}
+
+// selectRules returns the rules enabled by given comma-separated lists.
+func selectRules(enable, disable string) ([]*lint.Rule, error) {
+	names := func(list string) (map[string]bool, error) {
+		m := map[string]bool{}
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if lint.Lookup(name) == nil {
+				return nil, fmt.Errorf("unknown rule %q", name)
+			}
+			m[name] = true
+		}
+		return m, nil
+	}
+	enabled, err := names(enable)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := names(disable)
+	if err != nil {
+		return nil, err
+	}
+	rules := []*lint.Rule{}
+	for _, r := range lint.Rules() {
+		if (len(enabled) == 0 || enabled[r.Name]) && disabled[r.Name] == false {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
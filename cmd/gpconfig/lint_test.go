@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cbonello/gp-config/lint"
+	. "launchpad.net/gocheck"
+)
+
+type (
+	LintTests struct{}
+)
+
+var (
+	_ = Suite(&LintTests{})
+)
+
+const suspicious = "[db]\n\tpassword = \"x\"\n\tport = 1\n\tport = 2\n"
+
+// gpconfig lint: human output.
+func (lt *LintTests) TestLint1(c *C) {
+	code, stdout, _ := runApp(suspicious, "lint")
+	c.Check(code, Equals, exitError)
+	c.Check(stdout, Equals,
+		"<standard input>:2:13: 'db.password': secret stored in plain text (plaintext-secret)\n"+
+			"<standard input>:4:2: 'db.port': option redefined (previous declaration at 3:2) (duplicate-option)\n")
+
+	code, stdout, _ = runApp("[db]\n\tport = 1\n", "lint")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "")
+
+	dir := c.MkDir()
+	fn := writeFile(c, dir, "a.cfg", "a = ")
+	code, _, stderr := runApp("", "lint", dir)
+	c.Check(code, Equals, exitError)
+	c.Check(stderr, Matches, "(?s)"+fn+":1:5: unexpected end-of-file\n.*")
+}
+
+// gpconfig lint: rule selection.
+func (lt *LintTests) TestLint2(c *C) {
+	code, stdout, _ := runApp(suspicious, "lint", "-disable", "plaintext-secret")
+	c.Check(code, Equals, exitError)
+	c.Check(stdout, Matches, ".*duplicate-option.\n")
+
+	code, stdout, _ = runApp(suspicious, "lint", "-enable", "plaintext-secret,mixed-array")
+	c.Check(code, Equals, exitError)
+	c.Check(stdout, Matches, ".*plaintext-secret.\n")
+
+	code, _, _ = runApp(suspicious, "lint", "-enable", "plaintext-secret", "-disable", "plaintext-secret")
+	c.Check(code, Equals, exitOK)
+
+	code, _, stderr := runApp(suspicious, "lint", "-enable", "foo")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Equals, "gpconfig: unknown rule \"foo\"\n")
+
+	code, stdout, _ = runApp("", "lint", "-rules")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Matches, "(?s)duplicate-option +option declared twice.*")
+}
+
+// gpconfig lint -json.
+func (lt *LintTests) TestLint3(c *C) {
+	dir := c.MkDir()
+	fn1 := writeFile(c, dir, "a.cfg", suspicious)
+	fn2 := writeFile(c, dir, "b.cfg", "a = 'b'")
+	code, stdout, _ := runApp("", "lint", "-json", fn1, fn2)
+	c.Check(code, Equals, exitError)
+	issues := []lint.Issue{}
+	c.Assert(json.Unmarshal([]byte(stdout), &issues), IsNil)
+	c.Assert(issues, HasLen, 3)
+	c.Check(issues[0], Equals, lint.Issue{Filename: fn1, Line: 2, Column: 13,
+		Rule: "plaintext-secret", Message: "'db.password': secret stored in plain text"})
+	c.Check(issues[2], Equals, lint.Issue{Filename: fn2, Line: 1, Column: 5,
+		Rule: "syntax", Message: "'a': unexpected ''' character"})
+
+	code, stdout, _ = runApp("a = 1", "lint", "-json")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "[]\n")
+}
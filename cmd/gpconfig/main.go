@@ -0,0 +1,166 @@
+// Command gpconfig manipulates gp-config configuration files.
+//
+// Usage:
+//
+//	gpconfig <command> [arguments]
+//
+// Commands are:
+//
+//	fmt     format configuration files
+//	lint    report suspicious constructs
+//	get     print the value of an option
+//	list    list options or sections
+//	set     set an option in a file
+//	unset   remove an option from a file
+//	diff    compare configurations
+//	convert convert configurations to other formats
+//	encrypt encrypt a value
+//	decrypt decrypt a value
+//
+// Run "gpconfig help <command>" for the usage of a command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Extension of configuration files looked up in directories.
+const configExt = ".cfg"
+
+// Exit codes.
+const (
+	exitOK       = 0 // Success.
+	exitError    = 1 // Command failed.
+	exitUsage    = 2 // Invalid command line.
+	exitNotFound = 3 // Option or section not found.
+)
+
+type (
+	// app holds the standard streams of the command; tests redirect them.
+	app struct {
+		stdin  io.Reader
+		stdout io.Writer
+		stderr io.Writer
+	}
+
+	command struct {
+		name  string
+		args  string // Synopsis of arguments.
+		short string // One-line description.
+		run   func(a *app, args []string) int
+	}
+)
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"fmt", "[-l] [-d] [-w] [path ...]", "format configuration files", (*app).runFmt},
+		{"lint", "[-enable rules] [-disable rules] [-json] [-rules] [path ...]", "report suspicious constructs", (*app).runLint},
+		{"get", "[-f file]... [-json] option", "print the value of an option", (*app).runGet},
+		{"list", "[-f file]... [-json] [-sections | section]", "list options or sections", (*app).runList},
+		{"set", "[-case-sensitive] [-type type] file option value", "set an option in a file", (*app).runSet},
+		{"unset", "[-case-sensitive] file option", "remove an option from a file", (*app).runUnset},
+		{"diff", "[-json] (-a file... -b file... | file1 file2)", "compare configurations", (*app).runDiff},
+		{"convert", "[-f file]... [-from format] [-to format] [-prefix prefix]", "convert configurations to other formats", (*app).runConvert},
+		{"encrypt", "[-key-file file] [-genkey | value]", "encrypt a value", (*app).runEncrypt},
+		{"decrypt", "[-key-file file] [value]", "decrypt a value", (*app).runDecrypt},
+	}
+}
+
+func main() {
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(a.run(os.Args[1:]))
+}
+
+// run runs the command given on command line and returns the exit code.
+func (a *app) run(args []string) int {
+	if len(args) == 0 {
+		a.usage()
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		if len(args) > 1 {
+			if cmd := lookup(args[1]); cmd != nil {
+				fmt.Fprintf(a.stdout, "usage: gpconfig %s %s\n", cmd.name, cmd.args)
+				return exitOK
+			}
+		}
+		a.usage()
+		return exitOK
+	}
+	cmd := lookup(args[0])
+	if cmd == nil {
+		fmt.Fprintf(a.stderr, "gpconfig: unknown command %q\n", args[0])
+		a.usage()
+		return exitUsage
+	}
+	return cmd.run(a, args[1:])
+}
+
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (a *app) usage() {
+	fmt.Fprintf(a.stderr, "usage: gpconfig <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(a.stderr, "    %-8s%s\n", cmd.name, cmd.short)
+	}
+}
+
+// flagSet returns the flag set of given command; errors are reported on
+// stderr.
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		cmd := lookup(name)
+		fmt.Fprintf(a.stderr, "usage: gpconfig %s %s\n", cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// report prints given error on stderr; configuration errors are printed
+// with the offending line of input.
+func (a *app) report(err error) {
+	var cerr *config.ConfigurationError
+	if errors.As(err, &cerr) && cerr.Line > 0 {
+		fmt.Fprint(a.stderr, cerr.Diagnostic(false))
+		return
+	}
+	fmt.Fprintf(a.stderr, "gpconfig: %s\n", err)
+}
+
+// walkConfigs calls fn for given file, or for each configuration file
+// (*.cfg) found in given directory.
+func walkConfigs(path string, fn func(filename string, r io.Reader)) error {
+	return filepath.WalkDir(path, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (filename != path && filepath.Ext(filename) != configExt) {
+			return nil
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fn(filename, f)
+		return nil
+	})
+}
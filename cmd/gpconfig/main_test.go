@@ -0,0 +1,28 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+type (
+	MainTests struct{}
+)
+
+var (
+	_ = Suite(&MainTests{})
+)
+
+// run(): usage and unknown commands.
+func (mt *MainTests) TestRun1(c *C) {
+	code, _, stderr := runApp("")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Matches, "(?s)usage: gpconfig <command>.*fmt.*")
+
+	code, _, stderr = runApp("", "foo")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Matches, "(?s)gpconfig: unknown command \"foo\".*")
+
+	code, stdout, _ := runApp("", "help", "fmt")
+	c.Check(code, Equals, exitOK)
+	c.Check(stdout, Equals, "usage: gpconfig fmt [-l] [-d] [-w] [path ...]\n")
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"github.com/cbonello/gp-config/ast"
+	"github.com/cbonello/gp-config/format"
+	"github.com/cbonello/gp-config/scanner"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// editor modifies a configuration file in place; text outside of the
+	// edited declarations, comments included, is preserved.
+	editor struct {
+		filename      string
+		src           []byte
+		f             *ast.File
+		lines         []int // Offsets of line starts.
+		caseSensitive bool  // Section and option names are case sensitive.
+	}
+
+	// optionDecl is a declaration of an option.
+	optionDecl struct {
+		section *ast.Section // Nil for options declared before first section.
+		option  *ast.Option
+	}
+
+	// valueType is the type of an option value.
+	valueType struct {
+		kind  scanner.Kind
+		array bool
+	}
+)
+
+// Names of types given to -type.
+var typeNames = map[string]scanner.Kind{
+	"bool":     scanner.TkBool,
+	"int":      scanner.TkInt,
+	"float":    scanner.TkFloat,
+	"date":     scanner.TkDate,
+	"string":   scanner.TkString,
+	"duration": scanner.TkDuration,
+	"size":     scanner.TkSize,
+}
+
+// runSet implements "gpconfig set".
+func (a *app) runSet(args []string) int {
+	var typ string
+	var caseSensitive bool
+	flags := a.flagSet("set")
+	flags.StringVar(&typ, "type", "", "type of value (bool, int, float, date, string, duration, size, or []type for arrays); defaults to type of existing option")
+	flags.BoolVar(&caseSensitive, "case-sensitive", false, "section and option names are case sensitive")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return exitUsage
+	}
+	var t *valueType
+	if typ != "" {
+		name := strings.TrimPrefix(typ, "[]")
+		kind, found := typeNames[name]
+		if found == false {
+			fmt.Fprintf(a.stderr, "gpconfig: unknown type %q\n", typ)
+			return exitUsage
+		}
+		t = &valueType{kind, name != typ}
+	}
+
+	filename, option, value := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	return a.edit(filename, caseSensitive, func(e *editor) error {
+		return e.set(option, value, t)
+	})
+}
+
+// runUnset implements "gpconfig unset". It exits with exitNotFound if the
+// option is not declared in file.
+func (a *app) runUnset(args []string) int {
+	var caseSensitive bool
+	flags := a.flagSet("unset")
+	flags.BoolVar(&caseSensitive, "case-sensitive", false, "section and option names are case sensitive")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	filename, option := flags.Arg(0), flags.Arg(1)
+	return a.edit(filename, caseSensitive, func(e *editor) error {
+		return e.unset(option)
+	})
+}
+
+// edit applies fn to given file and writes the result if it is a valid
+// configuration.
+func (a *app) edit(filename string, caseSensitive bool, fn func(e *editor) error) int {
+	info, err := os.Stat(filename)
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	e, err := newEditor(filename, src)
+	if err != nil {
+		a.report(err)
+		return exitError
+	}
+	e.caseSensitive = caseSensitive
+	if err := fn(e); err != nil {
+		a.report(err)
+		if errors.Is(err, config.ErrUnknownOption) {
+			return exitNotFound
+		}
+		return exitError
+	}
+	// Last check before overwriting file.
+	cfg := newConfiguration()
+	cfg.SetCaseSensitive(caseSensitive)
+	if err := cfg.LoadReader(filename, bytes.NewReader(e.src)); err != nil {
+		a.report(err)
+		return exitError
+	}
+	if err := os.WriteFile(filename, e.src, info.Mode().Perm()); err != nil {
+		a.report(err)
+		return exitError
+	}
+	return exitOK
+}
+
+func newEditor(filename string, src []byte) (*editor, error) {
+	p, err := config.NewReaderParser(filename, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	f, cerr := p.ParseAST()
+	if cerr != nil {
+		return nil, cerr
+	}
+	e := &editor{filename: filename, src: src, f: f, lines: []int{0}}
+	for i, b := range src {
+		if b == '\n' {
+			e.lines = append(e.lines, i+1)
+		}
+	}
+	return e, nil
+}
+
+// offset returns the offset of given position in source.
+func (e *editor) offset(pos scanner.Position) int {
+	return e.lines[pos.Line-1] + pos.Column - 1
+}
+
+// lineStart returns the offset of the first character of given line, or the
+// length of source if line follows last line.
+func (e *editor) lineStart(line int) int {
+	if line > len(e.lines) {
+		return len(e.src)
+	}
+	return e.lines[line-1]
+}
+
+// replace replaces source between given offsets by text.
+func (e *editor) replace(from, to int, text string) {
+	src := make([]byte, 0, len(e.src)-(to-from)+len(text))
+	src = append(src, e.src[:from]...)
+	src = append(src, text...)
+	e.src = append(src, e.src[to:]...)
+}
+
+// sameName returns true if given section or option names match.
+func (e *editor) sameName(x, y string) bool {
+	if e.caseSensitive {
+		return x == y
+	}
+	return strings.EqualFold(x, y)
+}
+
+// splitPath returns the section and option names of given path.
+func splitPath(path string) (section, option string, err error) {
+	names, err := config.SplitPath(path)
+	if err != nil {
+		return "", "", err
+	}
+	switch len(names) {
+	case 1:
+		return "", names[0], nil
+	case 2:
+		return names[0], names[1], nil
+	}
+	return "", "", fmt.Errorf("'%s': sub-sections are not supported", path)
+}
+
+// declarations returns the declarations of given option, in order, and the
+// last declaration of its section.
+func (e *editor) declarations(section, option string) ([]optionDecl, *ast.Section) {
+	decls := []optionDecl{}
+	find := func(s *ast.Section, options []*ast.Option) {
+		for _, o := range options {
+			if e.sameName(o.Key.Name, option) {
+				decls = append(decls, optionDecl{s, o})
+			}
+		}
+	}
+	if section == "" {
+		find(nil, e.f.Options)
+		return decls, nil
+	}
+	var last *ast.Section
+	for _, s := range e.f.Sections {
+		if e.sameName(s.Name.Name, section) {
+			find(s, s.Options)
+			last = s
+		}
+	}
+	return decls, last
+}
+
+// set sets given option. Value is type checked against t, or against the
+// existing option if t is nil.
+func (e *editor) set(path, value string, t *valueType) error {
+	section, option, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	decls, s := e.declarations(section, option)
+	if t == nil && len(decls) > 0 {
+		t = typeOf(decls[len(decls)-1].option.Value)
+	}
+	text, err := literal(path, value, t)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(decls) > 0:
+		// Last declaration wins.
+		v := decls[len(decls)-1].option.Value
+		e.replace(e.offset(v.Pos()), e.offset(v.End()), text)
+	case s != nil:
+		// Declared after last option of section, with the same indentation.
+		last := s.Options[len(s.Options)-1]
+		indent := e.src[e.lineStart(last.Pos().Line):e.offset(last.Pos())]
+		e.insertLine(last.End().Line+1, fmt.Sprintf("%s%s = %s", indent,
+			config.JoinPath(option), text))
+	case section != "":
+		decl := fmt.Sprintf("[%s]\n\t%s = %s", config.JoinPath(section),
+			config.JoinPath(option), text)
+		if len(e.src) > 0 {
+			decl = "\n" + decl
+		}
+		e.insertLine(len(e.lines)+1, decl)
+	case len(e.f.Options) > 0:
+		last := e.f.Options[len(e.f.Options)-1]
+		e.insertLine(last.End().Line+1, fmt.Sprintf("%s = %s",
+			config.JoinPath(option), text))
+	case len(e.f.Sections) > 0:
+		// Declared before first section and its comments.
+		first := e.f.Sections[0]
+		line := first.Pos().Line
+		if first.Doc != nil {
+			line = first.Doc.Pos().Line
+		}
+		e.insertLine(line, fmt.Sprintf("%s = %s\n", config.JoinPath(option), text))
+	default:
+		e.insertLine(len(e.lines)+1, fmt.Sprintf("%s = %s", config.JoinPath(option), text))
+	}
+	return nil
+}
+
+// insertLine inserts given text as a new line before given line.
+func (e *editor) insertLine(line int, text string) {
+	offset := e.lineStart(line)
+	if offset == len(e.src) && offset > 0 && e.src[offset-1] != '\n' {
+		text = "\n" + text
+	}
+	e.replace(offset, offset, text+"\n")
+}
+
+// unset removes all declarations of given option, with the comments
+// preceding them. Sections left empty are removed.
+func (e *editor) unset(path string) error {
+	section, option, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	decls, _ := e.declarations(section, option)
+	if len(decls) == 0 {
+		return fmt.Errorf("'%s': %w", path, config.ErrUnknownOption)
+	}
+
+	// Sections whose options are all removed are removed.
+	count := map[*ast.Section]int{}
+	for _, d := range decls {
+		if d.section != nil {
+			count[d.section]++
+		}
+	}
+	removed := make([]bool, len(e.lines)+1)
+	for _, d := range decls {
+		first, last := e.docLine(d.option.Doc, d.option.Pos().Line), d.option.End().Line
+		if s := d.section; s != nil && count[s] == len(s.Options) {
+			first, last = e.docLine(s.Doc, s.Pos().Line), s.End().Line
+		}
+		for line := first; line <= last; line++ {
+			removed[line] = true
+		}
+	}
+	e.removeLines(removed)
+	return nil
+}
+
+// removeLines removes the lines flagged in removed, indexed by line number.
+// As by the formatter, blank lines surrounding removed lines are merged and
+// none are left at start or end of file.
+func (e *editor) removeLines(removed []bool) {
+	var src []byte
+	blank := true // Last line written is blank, or no line was written.
+	gap := false  // Lines were removed since last line written.
+	for line := 1; line <= len(e.lines); line++ {
+		if removed[line] {
+			gap = true
+			continue
+		}
+		text := e.src[e.lineStart(line):e.lineStart(line+1)]
+		isBlank := len(bytes.TrimSpace(text)) == 0
+		if gap && blank && isBlank {
+			continue
+		}
+		src = append(src, text...)
+		blank, gap = isBlank, false
+	}
+	if gap {
+		// Blank lines preceding removed lines at end of file.
+		for len(src) > 0 {
+			start := bytes.LastIndexByte(src[:len(src)-1], '\n') + 1
+			if len(bytes.TrimSpace(src[start:])) != 0 {
+				break
+			}
+			src = src[:start]
+		}
+	}
+	e.src = src
+}
+
+// docLine returns the first line of the comments of given group directly
+// preceding given line; that is, with no blank line in between.
+func (e *editor) docLine(doc *ast.CommentGroup, line int) int {
+	if doc == nil {
+		return line
+	}
+	for i := len(doc.List) - 1; i >= 0 && doc.List[i].Hash.Line == line-1; i-- {
+		line--
+	}
+	return line
+}
+
+// typeOf returns the type of given value.
+func typeOf(v ast.Value) *valueType {
+	switch v := v.(type) {
+	case *ast.Array:
+		return &valueType{v.Elements[0].Kind, true}
+	case *ast.Literal:
+		return &valueType{v.Kind, false}
+	}
+	return nil
+}
+
+func (t *valueType) String() string {
+	for name, kind := range typeNames {
+		if kind == t.kind {
+			if t.array {
+				return "[]" + name
+			}
+			return name
+		}
+	}
+	return t.kind.String()
+}
+
+// literal returns the source text of given value, type checked against t if
+// not nil. Strings may be given without quotes.
+func literal(path, value string, t *valueType) (string, error) {
+	v := parseValue(value)
+	if t != nil && t.kind == scanner.TkString && t.array == false {
+		if l, ok := v.(*ast.Literal); ok == false || l.Kind != scanner.TkString {
+			return quote(value), nil
+		}
+	}
+	if v == nil {
+		if t != nil {
+			return "", fmt.Errorf("'%s': invalid %s value %q", path, t, value)
+		}
+		// Strings may be given without quotes.
+		return quote(value), nil
+	}
+
+	literals := []*ast.Literal{}
+	switch v := v.(type) {
+	case *ast.Array:
+		literals = v.Elements
+	case *ast.Literal:
+		literals = append(literals, v)
+	}
+	texts := make([]string, len(literals))
+	for i, l := range literals {
+		texts[i] = format.Literal(l)
+		if t == nil {
+			continue
+		}
+		if _, isArray := v.(*ast.Array); isArray != t.array {
+			return "", fmt.Errorf("'%s': %s is not of type %s", path, value, t)
+		}
+		if l.Kind == scanner.TkInt && t.kind == scanner.TkFloat {
+			// Integers are converted as by the parser.
+			texts[i] = strconv.FormatFloat(float64(l.Value.(int64)), 'f', -1, 64) + ".0"
+		} else if l.Kind != t.kind {
+			return "", fmt.Errorf("'%s': %s is not of type %s", path, value, t)
+		}
+	}
+	if _, isArray := v.(*ast.Array); isArray {
+		return "[" + strings.Join(texts, ", ") + "]", nil
+	}
+	return texts[0], nil
+}
+
+// parseValue parses given value, returning nil if it is not a valid scalar
+// or array.
+func parseValue(value string) ast.Value {
+	p, err := config.NewReaderParser("", strings.NewReader("v = "+value))
+	if err != nil {
+		return nil
+	}
+	f, cerr := p.ParseAST()
+	if cerr != nil || len(f.Options) != 1 || f.Options[0].Comment != nil || f.Footer != nil {
+		return nil
+	}
+	return f.Options[0].Value
+}
+
+// quote returns given string as a string literal.
+func quote(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if r < ' ' || r == utf8.RuneError {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
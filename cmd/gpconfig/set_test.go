@@ -0,0 +1,237 @@
+package main
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+type (
+	SetTests struct{}
+)
+
+var (
+	_ = Suite(&SetTests{})
+)
+
+const edited = `# Application.
+version = [1, 0] # Major, minor.
+
+# Web server.
+[server]
+    host = "localhost" # Host.
+    # Port.
+    port = 80
+    ratio = 1.5
+
+[database]
+	user = "admin"
+`
+
+// set runs "gpconfig set" on a copy of edited and returns the exit code,
+// error output and resulting file.
+func set(c *C, args ...string) (int, string, string) {
+	fn := writeFile(c, c.MkDir(), "a.cfg", edited)
+	args = append([]string{"set"}, args...)
+	for i, arg := range args {
+		if arg == "FILE" {
+			args[i] = fn
+		}
+	}
+	code, _, stderr := runApp("", args...)
+	return code, stderr, readFile(c, fn)
+}
+
+// gpconfig set: existing options.
+func (st *SetTests) TestSet1(c *C) {
+	code, _, res := set(c, "FILE", "server.port", "8080")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Equals, `# Application.
+version = [1, 0] # Major, minor.
+
+# Web server.
+[server]
+    host = "localhost" # Host.
+    # Port.
+    port = 8080
+    ratio = 1.5
+
+[database]
+	user = "admin"
+`)
+
+	// Strings may be given without quotes.
+	code, _, res = set(c, "FILE", "SERVER.Host", `example.com`)
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*    host = "example.com" # Host.\n.*`)
+
+	code, _, res = set(c, "FILE", "server.host", `"a \"b\""`)
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*    host = "a \\"b\\"" # Host.\n.*`)
+
+	code, _, res = set(c, "FILE", "version", "[1,2, 3]")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*\nversion = \[1, 2, 3\] # Major, minor.\n.*`)
+
+	// Integers are converted to floating-point numbers.
+	code, _, res = set(c, "FILE", "server.ratio", "2")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*\n    ratio = 2.0\n.*`)
+}
+
+// gpconfig set: new options.
+func (st *SetTests) TestSet2(c *C) {
+	code, _, res := set(c, "FILE", "server.timeout", "30s")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*\n    ratio = 1.5\n    timeout = 30s\n\n\[database\].*`)
+
+	code, _, res = set(c, "FILE", `"example.com".root`, "/var/www")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*\tuser = "admin"\n\n\["example.com"\]\n\troot = "/var/www"\n`)
+
+	code, _, res = set(c, "FILE", "name", "app")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s)# Application.\nversion = \[1, 0\] # Major, minor.\nname = "app"\n\n# Web server.\n.*`)
+
+	// Explicit type.
+	code, _, res = set(c, "FILE", "-type", "string", "database.port", "5432")
+	c.Check(code, Equals, exitUsage)
+	code, _, res = set(c, "-type", "string", "FILE", "database.port", "5432")
+	c.Check(code, Equals, exitOK)
+	c.Check(res, Matches, `(?s).*\tuser = "admin"\n\tport = "5432"\n`)
+}
+
+// gpconfig set: sections are created.
+func (st *SetTests) TestSet3(c *C) {
+	dir := c.MkDir()
+	fn := writeFile(c, dir, "a.cfg", "[a]\n\tb = 1")
+	code, _, _ := runApp("", "set", fn, "c.d", "true")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[a]\n\tb = 1\n\n[c]\n\td = true\n")
+
+	fn = writeFile(c, dir, "b.cfg", "")
+	code, _, _ = runApp("", "set", fn, "a", "1")
+	c.Check(code, Equals, exitOK)
+	code, _, _ = runApp("", "set", fn, "s.b", "1979-05-27T07:32:00Z")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "a = 1\n\n[s]\n\tb = 1979-05-27T07:32:00Z\n")
+}
+
+// gpconfig set: type checking.
+func (st *SetTests) TestSet4(c *C) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"FILE", "server.port", "abc"}, "gpconfig: 'server.port': invalid int value \"abc\"\n"},
+		{[]string{"FILE", "server.port", "1.5"}, "gpconfig: 'server.port': 1.5 is not of type int\n"},
+		{[]string{"FILE", "version", "1"}, "gpconfig: 'version': 1 is not of type \\[\\]int\n"},
+		{[]string{"FILE", "version", "[1, true]"}, "gpconfig: 'version': \\[1, true\\] is not of type \\[\\]int\n"},
+		{[]string{"-type", "[]bool", "FILE", "server.port", "true"}, "gpconfig: 'server.port': true is not of type \\[\\]bool\n"},
+		{[]string{"-type", "float", "FILE", "a", "[1.5, true]"}, "gpconfig: 'a': \\[1.5, true\\] is not of type float\n"},
+		{[]string{"FILE", "a", "[1.5, true]"}, "(?s).*a.cfg:3:11: cannot use type bool as type float64\n.*"},
+		{[]string{"FILE", "a.b.c", "1"}, "gpconfig: 'a.b.c': sub-sections are not supported\n"},
+	}
+
+	for _, t := range tests {
+		code, stderr, res := set(c, t.args...)
+		c.Check(code, Equals, exitError)
+		c.Check(stderr, Matches, t.err)
+		c.Check(res, Equals, edited)
+	}
+
+	code, stderr, _ := set(c, "-type", "foo", "FILE", "a", "1")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Equals, "gpconfig: unknown type \"foo\"\n")
+}
+
+// gpconfig unset.
+func (st *SetTests) TestUnset1(c *C) {
+	dir := c.MkDir()
+	fn := writeFile(c, dir, "a.cfg", edited)
+	code, _, _ := runApp("", "unset", fn, "server.port")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, `# Application.
+version = [1, 0] # Major, minor.
+
+# Web server.
+[server]
+    host = "localhost" # Host.
+    ratio = 1.5
+
+[database]
+	user = "admin"
+`)
+
+	// Empty sections are removed, with blank lines left at end of file.
+	code, _, _ = runApp("", "unset", fn, "database.user")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, `# Application.
+version = [1, 0] # Major, minor.
+
+# Web server.
+[server]
+    host = "localhost" # Host.
+    ratio = 1.5
+`)
+
+	// Blank lines left at start of file are removed.
+	code, _, _ = runApp("", "unset", fn, "version")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, `# Web server.
+[server]
+    host = "localhost" # Host.
+    ratio = 1.5
+`)
+
+	code, _, stderr := runApp("", "unset", fn, "server.port")
+	c.Check(code, Equals, exitNotFound)
+	c.Check(stderr, Equals, "gpconfig: 'server.port': unknown option\n")
+}
+
+// gpconfig unset: all declarations are removed.
+func (st *SetTests) TestUnset2(c *C) {
+	fn := writeFile(c, c.MkDir(), "a.cfg", "[a]\n\tb = 1\n[c]\n\td = 1\n\td = 2\n[a]\n\t# B.\n\tb = 2\n\te = 3")
+	code, _, _ := runApp("", "unset", fn, "a.b")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[c]\n\td = 1\n\td = 2\n[a]\n\te = 3")
+
+	code, _, _ = runApp("", "unset", fn, "c.d")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[a]\n\te = 3")
+}
+
+// gpconfig unset: blank lines surrounding removed lines are merged.
+func (st *SetTests) TestUnset3(c *C) {
+	fn := writeFile(c, c.MkDir(), "a.cfg", "a = 1\n\n[b]\n\tc = 1\n\n[d]\n\te = 1\n\n\tf = 2\n\n\tg = 3\n\n\n[h]\n\ti = 1\n")
+	code, _, _ := runApp("", "unset", fn, "b.c")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "a = 1\n\n[d]\n\te = 1\n\n\tf = 2\n\n\tg = 3\n\n\n[h]\n\ti = 1\n")
+
+	// Other blank lines are preserved.
+	code, _, _ = runApp("", "unset", fn, "d.f")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "a = 1\n\n[d]\n\te = 1\n\n\tg = 3\n\n\n[h]\n\ti = 1\n")
+}
+
+// gpconfig set, gpconfig unset: case-sensitive names.
+func (st *SetTests) TestCaseSensitive1(c *C) {
+	dir := c.MkDir()
+	fn := writeFile(c, dir, "a.cfg", "[s]\n\ta = 1\n\tA = 2\n[S]\n\ta = 3\n")
+	code, _, _ := runApp("", "set", "-case-sensitive", fn, "s.A", "4")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[s]\n\ta = 1\n\tA = 4\n[S]\n\ta = 3\n")
+	code, _, _ = runApp("", "set", "-case-sensitive", fn, "S.A", "5")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[s]\n\ta = 1\n\tA = 4\n[S]\n\ta = 3\n\tA = 5\n")
+
+	code, _, _ = runApp("", "unset", "-case-sensitive", fn, "S.a")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[s]\n\ta = 1\n\tA = 4\n[S]\n\tA = 5\n")
+	code, _, stderr := runApp("", "unset", "-case-sensitive", fn, "s.B")
+	c.Check(code, Equals, exitNotFound)
+	c.Check(stderr, Equals, "gpconfig: 's.B': unknown option\n")
+
+	// Names are case insensitive by default; last declaration wins.
+	code, _, _ = runApp("", "set", fn, "s.a", "6")
+	c.Check(code, Equals, exitOK)
+	c.Check(readFile(c, fn), Equals, "[s]\n\ta = 1\n\tA = 4\n[S]\n\tA = 6\n")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	. "launchpad.net/gocheck"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	TestingT(t)
+}
+
+// runApp runs gpconfig with given arguments and standard input. It returns
+// the exit code and the standard and error outputs.
+func runApp(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	a := &app{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	code := a.run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFile creates a file in dir and returns its path.
+func writeFile(c *C, dir, name, contents string) string {
+	fn := filepath.Join(dir, name)
+	c.Assert(os.WriteFile(fn, []byte(contents), 0644), IsNil)
+	return fn
+}
+
+// readFile returns the contents of given file.
+func readFile(c *C, fn string) string {
+	b, err := os.ReadFile(fn)
+	c.Assert(err, IsNil)
+	return string(b)
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"github.com/cbonello/gp-config/scanner"
+	"math"
+	"os"
 	"reflect"
 	"sort"
 	"strings"
@@ -12,9 +15,14 @@ import (
 type (
 	// ConfigurationError records parsing errors.
 	ConfigurationError struct {
-		Filename     string // Filename.
-		Line, Column int    // Line and column.
-		msg          string
+		Filename           string // Filename.
+		Line, Column       int    // Line and column.
+		EndLine, EndColumn int    // End of offending text (exclusive).
+		Hint               string // How to fix the error, may be empty.
+		Code               ErrorCode
+		msg                string
+		source             string // Offending line of input.
+		err                error  // Underlying error, if any.
 	}
 
 	configurationSections map[string]struct{}
@@ -24,10 +32,22 @@ type (
 	configurationValue struct {
 		ctype configurationType // Data's type.
 		value interface{}       // Data.
+		name  string            // Option's path as first declared.
+		seq   int               // Declaration order.
+		// Profile that supplied the value, as declared; empty if the value
+		// was declared in a base section.
+		profile string
+		// Stands for the value of a secret in output: its SecretRef, or
+		// its encrypted form; nil if the value is not a secret.
+		masked interface{}
 	}
 
 	configurationType uint8
 
+	// Size is a number of bytes declared with a size literal such as 512B,
+	// 64kB or 10MiB.
+	Size int64
+
 	// Configuration context.
 	Configuration struct {
 		sync.RWMutex
@@ -35,6 +55,22 @@ type (
 		sections configurationSections
 		// List of options declared.
 		options configurationOptions
+		// Option and section names are case sensitive?
+		caseSensitive bool
+		// Key of encrypted values; see SetKeyProvider.
+		keys KeyProvider
+		// Secrets are kept unresolved? See SetResolveSecrets.
+		rawSecrets bool
+		// Active profile; see SetProfile.
+		profile string
+		// Number of options declared so far.
+		seq int
+		// Sources added with AddSource, by increasing precedence, and
+		// options loaded before the first one.
+		sources []Source
+		base    *Configuration
+		// Serializes AddSource and Reload.
+		reload sync.Mutex
 		// Given following configuration:
 		//
 		// foo = "bar"
@@ -50,60 +86,88 @@ type (
 )
 
 const (
-	_ArrayType  configurationType = 1
-	_BoolType                     = 2
-	_IntType                      = 4
-	_FloatType                    = 8
-	_DateType                     = 16
-	_StringType                   = 32
+	_ArrayType    configurationType = 1
+	_BoolType                       = 2
+	_IntType                        = 4
+	_FloatType                      = 8
+	_DateType                       = 16
+	_StringType                     = 32
+	_DurationType                   = 64
+	_SizeType                       = 128
 )
 
 var (
-	sliceType  = reflect.Slice
-	boolType   = reflect.Bool
-	intType    = reflect.Int64
-	floatType  = reflect.Float64
-	dateType   = reflect.TypeOf((*time.Time)(nil)).Elem()
-	stringType = reflect.String
-	ptrType    = reflect.Ptr
-	structType = reflect.Struct
+	sliceType = reflect.Slice
+	boolType  = reflect.Bool
+	intType   = reflect.Int64
+	floatType = reflect.Float64
+	dateType  = reflect.TypeOf((*time.Time)(nil)).Elem()
+	// Checked before intType since underlying type of both is int64.
+	durationType = reflect.TypeOf(time.Duration(0))
+	sizeType     = reflect.TypeOf(Size(0))
+	// Type of the references standing for secrets in output.
+	secretRefType = reflect.TypeOf(SecretRef(""))
+	stringType    = reflect.String
+	ptrType       = reflect.Ptr
+	structType    = reflect.Struct
 )
 
-// NewConfiguration creates a new configuration context.
+// NewConfiguration creates a new configuration context. The active profile
+// is read from the environment variable named by ProfileEnv.
 func NewConfiguration() (c *Configuration) {
 	return &Configuration{
 		sections: configurationSections{},
 		options:  configurationOptions{},
+		profile:  os.Getenv(ProfileEnv),
 	}
 }
 
-// LoadFile loads the configuration stored in given file.
-func (c *Configuration) LoadFile(filename string) (err *ConfigurationError) {
-	p, err0 := NewParser(filename)
-	if err0 != nil {
-		return &ConfigurationError{
-			Filename: filename,
-			Line:     0,
-			Column:   0,
-			msg:      err0.Error(),
-		}
-	}
-	if p != nil {
-		if err1 := p.Parse(c); err1 != nil {
-			return err1
-		}
+// LoadFile loads the configuration stored in given file. The format of the
+// file is chosen by extension (see RegisterFormat); files whose extension is
+// not registered are read in native format. Errors are reported as
+// *ConfigurationError; a missing file is reported with code ErrNotFound and
+// other read errors with code ErrIO. Both unwrap to the error returned by the
+// operating system. See Load for optional files and search paths.
+func (c *Configuration) LoadFile(filename string) error {
+	_, err := c.Load(filename, nil)
+	return err
+}
+
+// LoadString loads the configuration stored in given string. Errors are
+// reported as *ConfigurationError.
+func (c *Configuration) LoadString(contents string) error {
+	if err := NewStringParser(contents).Parse(c); err != nil {
+		return err
 	}
 	return nil
 }
 
-// LoadString loads the configuration stored in given string.
-func (c *Configuration) LoadString(contents string) (err *ConfigurationError) {
-	if p := NewStringParser(contents); p != nil {
-		if err = p.Parse(c); err != nil {
-			return err
+// SetCaseSensitive sets whether section and option names are case sensitive
+// or not. Names are case insensitive by default; in both modes, String()
+// reports names as first declared. Options already loaded are renamed
+// accordingly; when switching to case-insensitive mode, the last declared of
+// options that differ only by case wins.
+func (c *Configuration) SetCaseSensitive(sensitive bool) {
+	if c != nil {
+		c.Lock()
+		defer c.Unlock()
+		if c.caseSensitive == sensitive {
+			return
+		}
+		c.caseSensitive = sensitive
+		values := make([]configurationValue, 0, len(c.options))
+		for _, v := range c.options {
+			values = append(values, v)
+		}
+		sort.Sort(bySeq(values))
+		c.sections = configurationSections{}
+		c.options = configurationOptions{}
+		for _, v := range values {
+			key := c.key(v.name)
+			c.sections[c.getSection(key)] = struct{}{}
+			c.options[key] = v
 		}
 	}
-	return nil
 }
 
 // Len returns the number of options defined.
@@ -121,7 +185,7 @@ func (c *Configuration) HasOption(option string) (result bool) {
 	if c != nil {
 		c.RLock()
 		defer c.RUnlock()
-		_, result = c.options[strings.ToLower(option)]
+		_, result = c.options[c.key(option)]
 	}
 	return result
 }
@@ -145,36 +209,25 @@ func (c *Configuration) IsSection(section string) bool {
 	if c != nil {
 		c.RLock()
 		defer c.RUnlock()
-		section = strings.ToLower(section)
-		for s := range c.sections {
-			if section == s {
-				return true
-			}
-		}
+		_, found := c.sections[c.key(section)]
+		return found
 	}
 	return false
 }
 
 // Options returns the options defined in given section sorted in ascending
-// order. Optionsglobally  defined are returned if given section is an empty
-// string.
+// order. Options globally defined are returned if given section is an empty
+// string. Section and option names that are not identifiers are quoted (see
+// JoinPath).
 func (c *Configuration) Options(section string) (options []string) {
 	options = []string{}
 	if c != nil {
 		c.RLock()
 		defer c.RUnlock()
-		if section = strings.ToLower(section); section == "" {
-			// Look for options defined outside of a section.
-			for o := range c.options {
-				if strings.Index(o, ".") == -1 {
-					options = append(options, o)
-				}
-			}
-		} else {
-			for o := range c.options {
-				if strings.HasPrefix(o, section) {
-					options = append(options, o)
-				}
+		section = c.key(section)
+		for o := range c.options {
+			if c.getSection(o) == section {
+				options = append(options, o)
 			}
 		}
 		sort.Strings(options)
@@ -184,14 +237,15 @@ func (c *Configuration) Options(section string) (options []string) {
 
 // Get returns the value (scalar or array) associated with given option name,
 // or nil if option is undefined. option is a dot-separated path (e.g.
-// section.option).
+// section.option); names containing dots must be quoted (e.g.
+// "example.com".port, see JoinPath).
 func (c *Configuration) Get(option string) (interface{}, error) {
 	if c != nil {
 		if opt := c.getOption(option); opt != nil {
 			return opt.value, nil
 		}
 	}
-	return nil, fmt.Errorf("'%s': unknown option", option)
+	return nil, unknownOptionError(option)
 }
 
 // GetBool returns the boolean value associated with given option name. An
@@ -203,10 +257,10 @@ func (c *Configuration) GetBool(option string) (bool, error) {
 			if opt.ctype == _BoolType {
 				return opt.value.(bool), nil
 			}
-			return false, fmt.Errorf("'%s': not a boolean", option)
+			return false, newOptionError(ErrTypeMismatch, option, "not a boolean")
 		}
 	}
-	return false, fmt.Errorf("'%s': unknown option", option)
+	return false, unknownOptionError(option)
 }
 
 // GetBoolDefault is similar to GetBool but given default value is returned
@@ -230,10 +284,10 @@ func (c *Configuration) GetInt(option string) (int64, error) {
 			if opt.ctype == _IntType {
 				return opt.value.(int64), nil
 			}
-			return 0, fmt.Errorf("'%s': not an integer", option)
+			return 0, newOptionError(ErrTypeMismatch, option, "not an integer")
 		}
 	}
-	return 0, fmt.Errorf("'%s': unknown option", option)
+	return 0, unknownOptionError(option)
 }
 
 // GetIntDefault is similar to GetInt but given default value is returned
@@ -258,10 +312,10 @@ func (c *Configuration) GetFloat(option string) (float64, error) {
 			if opt.ctype == _FloatType {
 				return opt.value.(float64), nil
 			}
-			return 0, fmt.Errorf("'%s': not a floating-point number", option)
+			return 0, newOptionError(ErrTypeMismatch, option, "not a floating-point number")
 		}
 	}
-	return 0, fmt.Errorf("'%s': unknown option", option)
+	return 0, unknownOptionError(option)
 }
 
 // GetFloatDefault is similar to GetFloat but given default value is returned
@@ -285,10 +339,10 @@ func (c *Configuration) GetDate(option string) (time.Time, error) {
 			if opt.ctype == _DateType {
 				return opt.value.(time.Time), nil
 			}
-			return time.Now(), fmt.Errorf("'%s': not a date", option)
+			return time.Now(), newOptionError(ErrTypeMismatch, option, "not a date")
 		}
 	}
-	return time.Now(), fmt.Errorf("'%s': unknown option", option)
+	return time.Now(), unknownOptionError(option)
 }
 
 // GetDateDefault is similar to GetDate but given default value is returned
@@ -312,10 +366,10 @@ func (c *Configuration) GetString(option string) (string, error) {
 			if opt.ctype == _StringType {
 				return opt.value.(string), nil
 			}
-			return "", fmt.Errorf("'%s': not a string", option)
+			return "", newOptionError(ErrTypeMismatch, option, "not a string")
 		}
 	}
-	return "", fmt.Errorf("'%s': unknown option", option)
+	return "", unknownOptionError(option)
 }
 
 // GetStringDefault is similar to GetString but given default value is returned
@@ -331,6 +385,60 @@ func (c *Configuration) GetStringDefault(option string, dfault string) string {
 	return dfault
 }
 
+// GetDuration returns the duration associated with given option name. An
+// error is flagged if option does not record a duration or it is undefined.
+func (c *Configuration) GetDuration(option string) (time.Duration, error) {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _DurationType {
+				return opt.value.(time.Duration), nil
+			}
+			return 0, newOptionError(ErrTypeMismatch, option, "not a duration")
+		}
+	}
+	return 0, unknownOptionError(option)
+}
+
+// GetDurationDefault is similar to GetDuration but given default value is
+// returned if option does not exist or is of wrong type.
+func (c *Configuration) GetDurationDefault(option string, dfault time.Duration) time.Duration {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _DurationType {
+				return opt.value.(time.Duration)
+			}
+		}
+	}
+	return dfault
+}
+
+// GetSize returns the size associated with given option name. An error is
+// flagged if option does not record a size or it is undefined.
+func (c *Configuration) GetSize(option string) (Size, error) {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _SizeType {
+				return opt.value.(Size), nil
+			}
+			return 0, newOptionError(ErrTypeMismatch, option, "not a size")
+		}
+	}
+	return 0, unknownOptionError(option)
+}
+
+// GetSizeDefault is similar to GetSize but given default value is returned
+// if option does not exist or is of wrong type.
+func (c *Configuration) GetSizeDefault(option string, dfault Size) Size {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _SizeType {
+				return opt.value.(Size)
+			}
+		}
+	}
+	return dfault
+}
+
 // GetBoolArray returns the array of booleans associated with given option
 // name. An error is flagged if option does not record an array of booleans
 // or it is undefined.
@@ -340,10 +448,10 @@ func (c *Configuration) GetBoolArray(option string) ([]bool, error) {
 			if opt.ctype == _ArrayType|_BoolType {
 				return opt.value.([]bool), nil
 			}
-			return nil, fmt.Errorf("'%s': not an array of booleans", option)
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of booleans")
 		}
 	}
-	return nil, fmt.Errorf("'%s': unknown option", option)
+	return nil, unknownOptionError(option)
 }
 
 // GetBoolArrayDefault is similar to GetBoolArray but given default value is
@@ -368,10 +476,10 @@ func (c *Configuration) GetIntArray(option string) ([]int64, error) {
 			if opt.ctype == _ArrayType|_IntType {
 				return opt.value.([]int64), nil
 			}
-			return nil, fmt.Errorf("'%s': not an array of integers", option)
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of integers")
 		}
 	}
-	return nil, fmt.Errorf("'%s': unknown option", option)
+	return nil, unknownOptionError(option)
 }
 
 // GetIntArrayDefault is similar to GetIntArray but given default value is
@@ -396,10 +504,10 @@ func (c *Configuration) GetFloatArray(option string) ([]float64, error) {
 			if opt.ctype == _ArrayType|_FloatType {
 				return opt.value.([]float64), nil
 			}
-			return nil, fmt.Errorf("'%s': not an array of floating-point numbers", option)
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of floating-point numbers")
 		}
 	}
-	return nil, fmt.Errorf("'%s': unknown option", option)
+	return nil, unknownOptionError(option)
 }
 
 // GetFloatArrayDefault is similar to GetFloatArray but given default value
@@ -424,10 +532,10 @@ func (c *Configuration) GetDateArray(option string) ([]time.Time, error) {
 			if opt.ctype == _ArrayType|_DateType {
 				return opt.value.([]time.Time), nil
 			}
-			return nil, fmt.Errorf("'%s': not an array of dates", option)
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of dates")
 		}
 	}
-	return nil, fmt.Errorf("'%s': unknown option", option)
+	return nil, unknownOptionError(option)
 }
 
 // GetDateArrayDefault is similar to GetDateArray but given default value
@@ -452,10 +560,10 @@ func (c *Configuration) GetStringArray(option string) ([]string, error) {
 			if opt.ctype == _ArrayType|_StringType {
 				return opt.value.([]string), nil
 			}
-			return nil, fmt.Errorf("'%s': not an array of strings", option)
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of strings")
 		}
 	}
-	return nil, fmt.Errorf("'%s': unknown option", option)
+	return nil, unknownOptionError(option)
 }
 
 // GetStringArrayDefault is similar to GetStringArray but given default value
@@ -471,33 +579,109 @@ func (c *Configuration) GetStringArrayDefault(option string, dfault []string) []
 	return dfault
 }
 
+// GetDurationArray returns the array of durations associated with given
+// option name. An error is flagged if option does not record an array of
+// durations or it is undefined.
+func (c *Configuration) GetDurationArray(option string) ([]time.Duration, error) {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _ArrayType|_DurationType {
+				return opt.value.([]time.Duration), nil
+			}
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of durations")
+		}
+	}
+	return nil, unknownOptionError(option)
+}
+
+// GetDurationArrayDefault is similar to GetDurationArray but given default
+// value is returned if option does not exist or is of wrong type.
+func (c *Configuration) GetDurationArrayDefault(option string, dfault []time.Duration) []time.Duration {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _ArrayType|_DurationType {
+				return opt.value.([]time.Duration)
+			}
+		}
+	}
+	return dfault
+}
+
+// GetSizeArray returns the array of sizes associated with given option
+// name. An error is flagged if option does not record an array of sizes or
+// it is undefined.
+func (c *Configuration) GetSizeArray(option string) ([]Size, error) {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _ArrayType|_SizeType {
+				return opt.value.([]Size), nil
+			}
+			return nil, newOptionError(ErrTypeMismatch, option, "not an array of sizes")
+		}
+	}
+	return nil, unknownOptionError(option)
+}
+
+// GetSizeArrayDefault is similar to GetSizeArray but given default value is
+// returned if option does not exist or is of wrong type.
+func (c *Configuration) GetSizeArrayDefault(option string, dfault []Size) []Size {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.ctype == _ArrayType|_SizeType {
+				return opt.value.([]Size)
+			}
+		}
+	}
+	return dfault
+}
+
+// buildOptionPath builds the path of given option; section is a path and
+// option a name that is quoted if needed.
 func buildOptionPath(section, option string) string {
 	if section == "" {
-		return option
+		return quoteKey(option)
 	}
-	return section + "." + option
+	return section + "." + quoteKey(option)
 }
 
-func (c *Configuration) getOption(key string) *configurationValue {
-	key = strings.ToLower(key)
+// key returns the internal representation of given option or section path.
+// Caller must hold the lock.
+func (c *Configuration) key(path string) string {
+	if c.caseSensitive == false {
+		path = strings.ToLower(path)
+	}
+	return canonicalPath(path)
+}
 
+func (c *Configuration) getOption(key string) *configurationValue {
 	c.RLock()
 	defer c.RUnlock()
+	key = c.key(key)
 	if value, found := c.options[key]; found == true {
 		return &value
 	}
 	return nil
 }
 
-func (c *Configuration) setOption(key string, value interface{}) {
-	key = strings.ToLower(key)
+func (c *Configuration) setOption(name string, value interface{}) {
+	c.storeValue(name, value, false)
+}
+
+// overrideOption is similar to setOption for values overriding declarations
+// (environment variables, flags, Set) rather than declaring options: an
+// option holding a secret stays one, still written out as declared.
+func (c *Configuration) overrideOption(name string, value interface{}) {
+	c.storeValue(name, value, true)
+}
 
+func (c *Configuration) storeValue(name string, value interface{}, override bool) {
 	// May be nil if an error was detected during parsing. Can be safely
 	// ignored since an error was (or will be) generated by the parser.
 	if value != nil {
 		// Records section.
 		c.Lock()
 		defer c.Unlock()
+		key := c.key(name)
 
 		// Adds to list of sections if new one.
 		s := c.getSection(key)
@@ -510,21 +694,79 @@ func (c *Configuration) setOption(key string, value interface{}) {
 		// options to speed up read operations. Reflection is fine during
 		// parsing since it is usually only performed during startup.
 		rv := reflect.ValueOf(value)
+		var v configurationValue
 		if rv.Kind() == sliceType {
-			c.options[key] = c.setArray(key, rv)
-
+			v = c.setArray(key, rv)
 		} else {
-			c.options[key] = c.setValue(key, rv)
+			v = c.setValue(key, rv)
+		}
+		if old, exists := c.options[key]; exists && override {
+			v.masked = old.masked
 		}
+		c.storeOption(key, canonicalPath(name), v)
 	}
 }
 
+// storeOption records an option. Caller must hold the lock.
+func (c *Configuration) storeOption(key, name string, v configurationValue) {
+	// An updated option keeps its original spelling and position.
+	if old, exists := c.options[key]; exists {
+		v.name, v.seq = old.name, old.seq
+	} else {
+		c.seq++
+		v.name, v.seq = name, c.seq
+	}
+	c.options[key] = v
+}
+
+// merge applies options of src to c in declaration order. Options of
+// profile sections are applied last, to the base sections, if their profile
+// is active; they are ignored otherwise.
+func (c *Configuration) merge(src *Configuration) {
+	values := make([]configurationValue, 0, len(src.options))
+	for _, v := range src.options {
+		values = append(values, v)
+	}
+	sort.Sort(bySeq(values))
+
+	c.Lock()
+	defer c.Unlock()
+	var overrides []configurationValue
+	for _, v := range values {
+		if path, profile, isProfile := profileOption(v.name); isProfile {
+			if c.isActiveProfile(profile) {
+				v.name, v.profile = path, profile
+				overrides = append(overrides, v)
+			}
+			continue
+		}
+		c.storeMerged(v)
+	}
+	for _, v := range overrides {
+		c.storeMerged(v)
+	}
+}
+
+// storeMerged stores an option read from another configuration. Caller must
+// hold the lock.
+func (c *Configuration) storeMerged(v configurationValue) {
+	key := c.key(v.name)
+	c.sections[c.getSection(key)] = struct{}{}
+	c.storeOption(key, v.name, v)
+}
+
 func (c *Configuration) setValue(key string, rv reflect.Value) configurationValue {
 	value := configurationValue{}
 
 	if rv.Type() == dateType {
 		value.ctype = _DateType
 		value.value = rv.Interface().(time.Time)
+	} else if rv.Type() == durationType {
+		value.ctype = _DurationType
+		value.value = rv.Interface().(time.Duration)
+	} else if rv.Type() == sizeType {
+		value.ctype = _SizeType
+		value.value = rv.Interface().(Size)
 	} else {
 		switch rv.Kind() {
 		case boolType:
@@ -555,6 +797,20 @@ func (c *Configuration) setArray(key string, rv reflect.Value) configurationValu
 			a = append(a, rv.Index(i).Interface().(time.Time))
 		}
 		value.value = a
+	} else if reflect.TypeOf(rv.Index(0).Interface()) == durationType {
+		value.ctype = _ArrayType | _DurationType
+		a := []time.Duration{}
+		for i := 0; i < rv.Len(); i++ {
+			a = append(a, rv.Index(i).Interface().(time.Duration))
+		}
+		value.value = a
+	} else if reflect.TypeOf(rv.Index(0).Interface()) == sizeType {
+		value.ctype = _ArrayType | _SizeType
+		a := []Size{}
+		for i := 0; i < rv.Len(); i++ {
+			a = append(a, rv.Index(i).Interface().(Size))
+		}
+		value.value = a
 	} else {
 		switch reflect.ValueOf(rv.Index(0).Interface()).Kind() {
 		case boolType:
@@ -593,10 +849,16 @@ func (c *Configuration) setArray(key string, rv reflect.Value) configurationValu
 }
 
 func (c *Configuration) getSection(option string) string {
-	s := strings.Split(option, ".")
+	if strings.IndexByte(option, '"') == -1 {
+		if i := strings.IndexByte(option, '.'); i != -1 {
+			return option[:i]
+		}
+		return ""
+	}
+	s, err := SplitPath(option)
 	// Size is 1 if option was declared outside of a section.
-	if len(s) > 1 {
-		return s[0]
+	if err == nil && len(s) > 1 {
+		return JoinPath(s[:len(s)-1]...)
 	}
 	return ""
 }
@@ -609,54 +871,69 @@ func (c *ConfigurationError) Error() string {
 	return ""
 }
 
-// String dumps a configuration to a string.
+// String dumps a configuration to a string. Options are dumped in
+// declaration order, with names spelled as first declared.
 func (c *Configuration) String() string {
+	c.RLock()
+	defer c.RUnlock()
+	values := make([]configurationValue, 0, len(c.options))
+	for _, v := range c.options {
+		values = append(values, v)
+	}
+	sort.Sort(bySeq(values))
+
 	buf := ""
-	for k, v := range c.options {
-		rv := reflect.ValueOf(v.value)
-		if rv.Type() == dateType {
-			buf += fmt.Sprintf("%s = %s\n", k, valueToString(rv))
-		} else {
-			switch rv.Kind() {
-			case boolType:
-				buf += fmt.Sprintf("%s = %s\n", k, valueToString(rv))
-			case intType:
-				buf += fmt.Sprintf("%s = %s\n", k, valueToString(rv))
-			case floatType:
-				buf += fmt.Sprintf("%s = %s\n", k, valueToString(rv))
-			case stringType:
-				buf += fmt.Sprintf("%s = %s\n", k, valueToString(rv))
-			case sliceType:
-				buf += fmt.Sprintf("%s = [", k)
-				array := rv
-				for i := 0; i < array.Len(); i++ {
-					if i > 0 {
-						buf += ", "
-					}
-					buf += fmt.Sprintf("%s",
-						valueToString(reflect.ValueOf(array.Index(i).Interface())))
-				}
-				buf += fmt.Sprintf("]\n")
-			default:
-				panic(fmt.Sprintf("unexpected type '%s'",
-					reflect.TypeOf(v).Kind()))
-			}
-		}
+	for _, v := range values {
+		buf += fmt.Sprintf("%s = %s\n", v.name, formatValue(v.redacted()))
 	}
 	return buf
 }
 
+// formatValue formats an option value (scalar or array) as a literal.
+func formatValue(value interface{}) string {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != sliceType {
+		return valueToString(rv)
+	}
+	buf := "["
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf += ", "
+		}
+		buf += valueToString(reflect.ValueOf(rv.Index(i).Interface()))
+	}
+	return buf + "]"
+}
+
 func valueToString(v reflect.Value) string {
 	if v.Type() == dateType {
 		date := v.Interface().(time.Time).Format(time.RFC3339)
 		return fmt.Sprintf("%s", date)
 	}
+	if v.Type() == durationType {
+		return v.Interface().(time.Duration).String()
+	}
+	if v.Type() == sizeType {
+		return v.Interface().(Size).String()
+	}
+	if v.Type() == secretRefType {
+		return v.Interface().(SecretRef).String()
+	}
 	switch v.Kind() {
 	case boolType:
 		return fmt.Sprintf("%t", v.Bool())
 	case intType:
 		return fmt.Sprintf("%d", v.Int())
 	case floatType:
+		// Special values are dumped as literals accepted by the lexer.
+		switch f := v.Float(); {
+		case math.IsInf(f, 1):
+			return "inf"
+		case math.IsInf(f, -1):
+			return "-inf"
+		case math.IsNaN(f):
+			return "nan"
+		}
 		return fmt.Sprintf("%f", v.Float())
 	case stringType:
 		return fmt.Sprintf("\"%s\"", v.String())
@@ -683,6 +960,23 @@ func (c configurationType) String() string {
 		typ = "time.Time"
 	case _StringType:
 		typ = "string"
+	case _DurationType:
+		typ = "time.Duration"
+	case _SizeType:
+		typ = "config.Size"
 	}
 	return prefix + typ
 }
+
+// String formats a size using the largest unit that represents it exactly
+// (e.g. 10MiB, 64kB or 1000B). Output is a valid size literal.
+func (s Size) String() string {
+	return scanner.FormatSize(int64(s))
+}
+
+// bySeq sorts configuration values in declaration order.
+type bySeq []configurationValue
+
+func (a bySeq) Len() int           { return len(a) }
+func (a bySeq) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a bySeq) Less(i, j int) bool { return a[i].seq < a[j].seq }
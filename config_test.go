@@ -2787,6 +2787,87 @@ strings = ["foo", "bar"]
 	c.Check(value1, EqualSlice, []string{"bar", "foo"})
 }
 
+// GetDuration(): nil structure.
+func (ct *ConfigTests) TestGetDuration1(c *C) {
+	var cfg *config.Configuration
+
+	value, err := cfg.GetDuration("foo")
+	c.Check(value, Equals, time.Duration(0))
+	c.Check(err, ErrorMatches, "'foo': unknown option")
+}
+
+// GetDuration(): valid and wrong types.
+func (ct *ConfigTests) TestGetDuration2(c *C) {
+	contents := `
+[values]
+	timeout = 2h45m
+	integer = 12`
+
+	ct.createTestEnv(c, []string{contents})
+	defer ct.cleanTestEnv(c)
+
+	c.Check(ct.config.Len(), Equals, 2)
+
+	value, err := ct.config.GetDuration("values.timeout")
+	c.Check(err, IsNil)
+	c.Check(value, Equals, 165*time.Minute)
+	_, err = ct.config.GetDuration("values.integer")
+	c.Check(err, ErrorMatches, "'values.integer': not a duration")
+	value = ct.config.GetDurationDefault("values.integer", time.Second)
+	c.Check(value, Equals, time.Second)
+	_, err = ct.config.GetDurationArray("values.timeout")
+	c.Check(err, ErrorMatches, "'values.timeout': not an array of durations")
+	arr := ct.config.GetDurationArrayDefault("values.foo", []time.Duration{time.Hour})
+	c.Check(arr, EqualSlice, []time.Duration{time.Hour})
+}
+
+// GetSize(): nil structure.
+func (ct *ConfigTests) TestGetSize1(c *C) {
+	var cfg *config.Configuration
+
+	value, err := cfg.GetSize("foo")
+	c.Check(value, Equals, config.Size(0))
+	c.Check(err, ErrorMatches, "'foo': unknown option")
+}
+
+// GetSize(): valid and wrong types.
+func (ct *ConfigTests) TestGetSize2(c *C) {
+	contents := `
+[values]
+	max_body = 1GB
+	integer = 12`
+
+	ct.createTestEnv(c, []string{contents})
+	defer ct.cleanTestEnv(c)
+
+	c.Check(ct.config.Len(), Equals, 2)
+
+	value, err := ct.config.GetSize("values.max_body")
+	c.Check(err, IsNil)
+	c.Check(value, Equals, config.Size(1e9))
+	_, err = ct.config.GetSize("values.integer")
+	c.Check(err, ErrorMatches, "'values.integer': not a size")
+	value = ct.config.GetSizeDefault("values.integer", 1024)
+	c.Check(value, Equals, config.Size(1024))
+	_, err = ct.config.GetSizeArray("values.max_body")
+	c.Check(err, ErrorMatches, "'values.max_body': not an array of sizes")
+	arr := ct.config.GetSizeArrayDefault("values.foo", []config.Size{1})
+	c.Check(arr, EqualSlice, []config.Size{1})
+}
+
+// String(): durations and sizes are dumped as literals.
+func (ct *ConfigTests) TestGetDump2(c *C) {
+	contents := `sizes = [1000B, 1536B, 4MiB, 2kB]`
+
+	ct.createTestEnv(c, []string{contents})
+	defer ct.cleanTestEnv(c)
+
+	str := fmt.Sprintf("%s", ct.config)
+	c.Check(str, Equals, "sizes = [1kB, 1536B, 4MiB, 2kB]\n")
+	c.Check(config.Size(3<<30).String(), Equals, "3GiB")
+	c.Check(config.Size(0).String(), Equals, "0B")
+}
+
 // String(): Dump function.
 func (ct *ConfigTests) TestGetDump1(c *C) {
 	contents := `boolean = true
@@ -2804,3 +2885,76 @@ booleans = [true, false]`
 	str := fmt.Sprintf("%s", ct.config)
 	c.Check(str, Equals, contents+"\n")
 }
+
+// String(): original spelling and declaration order are preserved.
+func (ct *ConfigTests) TestGetDump3(c *C) {
+	contents := `
+[Server]
+	URL = "www.myurl.com"
+	Port = 80
+[server]
+	url = "www.example.com"
+	timeout = 1m0s`
+
+	ct.createTestEnv(c, []string{contents})
+	defer ct.cleanTestEnv(c)
+
+	c.Check(ct.config.Len(), Equals, 3)
+
+	str := fmt.Sprintf("%s", ct.config)
+	c.Check(str, Equals, "Server.URL = \"www.example.com\"\n"+
+		"Server.Port = 80\n"+
+		"server.timeout = 1m0s\n")
+}
+
+// SetCaseSensitive(): case-sensitive option names.
+func (ct *ConfigTests) TestCaseSensitive1(c *C) {
+	contents := `
+URL = "upper"
+url = "lower"
+[DB]
+	Name = "mydb"`
+
+	cfg := config.NewConfiguration()
+	cfg.SetCaseSensitive(true)
+	c.Assert(cfg.LoadString(contents), IsNil)
+
+	c.Check(cfg.Len(), Equals, 3)
+	c.Check(cfg.GetStringDefault("URL", ""), Equals, "upper")
+	c.Check(cfg.GetStringDefault("url", ""), Equals, "lower")
+	c.Check(cfg.HasOption("db.name"), Equals, false)
+	c.Check(cfg.HasOption("DB.Name"), Equals, true)
+	c.Check(cfg.IsSection("db"), Equals, false)
+	c.Check(cfg.Sections(), EqualSlice, []string{"", "DB"})
+	c.Check(cfg.Options(""), EqualSlice, []string{"URL", "url"})
+
+	type (
+		db struct {
+			Name string
+			name string
+		}
+	)
+	v := db{}
+	c.Check(cfg.Decode("DB", &v), IsNil)
+	c.Check(v.Name, Equals, "mydb")
+}
+
+// SetCaseSensitive(): switching modes renames loaded options.
+func (ct *ConfigTests) TestCaseSensitive2(c *C) {
+	contents := `
+URL = "upper"
+url = "lower"`
+
+	cfg := config.NewConfiguration()
+	cfg.SetCaseSensitive(true)
+	c.Assert(cfg.LoadString(contents), IsNil)
+	c.Check(cfg.Len(), Equals, 2)
+
+	cfg.SetCaseSensitive(false)
+	c.Check(cfg.Len(), Equals, 1)
+	c.Check(cfg.GetStringDefault("Url", ""), Equals, "lower")
+
+	cfg.SetCaseSensitive(true)
+	c.Check(cfg.Len(), Equals, 1)
+	c.Check(cfg.HasOption("url"), Equals, true)
+}
@@ -1,31 +1,97 @@
 package config
 
 import (
+	"encoding"
 	"fmt"
+	"net/url"
 	"reflect"
+	"strconv"
+	"sync"
 	"time"
 )
 
+type (
+	// Unmarshaler is implemented by types that can decode an option value
+	// themselves. value is the raw option value; bool, int64, float64,
+	// time.Time, string, or a slice of one of those types. path is the
+	// dot-separated path of the option being decoded.
+	Unmarshaler interface {
+		UnmarshalConfig(path string, value interface{}) error
+	}
+
+	// DecoderFunc converts the raw value of option path to a value
+	// assignable to the type it was registered for with RegisterDecoder.
+	DecoderFunc func(path string, value interface{}) (interface{}, error)
+)
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	// Decoders registered for third-party types.
+	decoders = struct {
+		sync.RWMutex
+		m map[reflect.Type]DecoderFunc
+	}{
+		m: map[reflect.Type]DecoderFunc{
+			reflect.TypeOf((*url.URL)(nil)): decodeURL,
+		},
+	}
+)
+
+// RegisterDecoder registers a decoder for given type. Decode calls fn
+// whenever a destination field (or slice element) has exactly type typ.
+// Registered decoders take precedence over Unmarshaler and
+// encoding.TextUnmarshaler implementations. A nil fn removes the decoder
+// registered for typ.
+func RegisterDecoder(typ reflect.Type, fn DecoderFunc) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	if fn == nil {
+		delete(decoders.m, typ)
+	} else {
+		decoders.m[typ] = fn
+	}
+}
+
+func lookupDecoder(typ reflect.Type) DecoderFunc {
+	decoders.RLock()
+	defer decoders.RUnlock()
+	return decoders.m[typ]
+}
+
+func decodeURL(path string, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if ok == false {
+		return nil, fmt.Errorf("not a string")
+	}
+	return url.Parse(s)
+}
+
 // Decode initializes structPtr with contents of given section. Function does
 // not support circular types; it will loop forever.
+//
+// Fields whose type has a decoder registered with RegisterDecoder, or that
+// implements Unmarshaler or encoding.TextUnmarshaler, are decoded by that
+// decoder or method; the same applies to slice elements.
 func (c *Configuration) Decode(section string, structPtr interface{}) (err error) {
 	if c != nil {
 		if c.IsSection(section) == false {
-			return fmt.Errorf("'%s': unknown section", section)
+			return newOptionError(ErrUnknownSection, section, "unknown section")
 		}
 		if structPtr == nil {
-			return fmt.Errorf("structure argument cannot be a nil value")
+			return newArgumentError("structure argument cannot be a nil value")
 		}
 		structPtrType := reflect.TypeOf(structPtr)
 		if structPtrType.Kind() != ptrType {
-			return fmt.Errorf("structure argument is not a pointer")
+			return newArgumentError("structure argument is not a pointer")
 		}
 		structPtrVal := reflect.ValueOf(structPtr)
 		if structPtrVal.IsNil() {
-			return fmt.Errorf("structure argument cannot be a nil pointer")
+			return newArgumentError("structure argument cannot be a nil pointer")
 		}
 		if structPtrVal.Elem().Kind() != structType {
-			return fmt.Errorf("structure argument is not a pointer to a structure")
+			return newArgumentError("structure argument is not a pointer to a structure")
 		}
 		err = c.doDecode(section, structPtrVal.Elem(), structPtrType.Elem())
 	}
@@ -48,8 +114,7 @@ func (c *Configuration) doDecode(section string, val reflect.Value, typ reflect.
 				}
 
 			} else {
-				return fmt.Errorf(
-					"'%s': embedded pointer fields are not supported yet",
+				return newArgumentError("'%s': embedded pointer fields are not supported yet",
 					fieldType.Name)
 			}
 
@@ -82,14 +147,18 @@ func (c *Configuration) doDecode(section string, val reflect.Value, typ reflect.
 			// Path corresponds to an existing option?
 			if src := c.getOption(path); src != nil {
 				if fieldVal.IsValid() == false {
-					return fmt.Errorf("'%s': cannot set field's value",
+					return newArgumentError("'%s': cannot set field's value",
 						fieldType.Name)
 				}
 				if fieldVal.CanSet() == false {
-					return fmt.Errorf("'%s': cannot set value of unexported struct field",
+					return newArgumentError("'%s': cannot set value of unexported struct field",
 						fieldType.Name)
 				}
-				if fieldVal.Type().Kind() == sliceType {
+				if hasDecodeHook(fieldVal.Type()) {
+					if err := c.decodeHook(path, src, fieldVal); err != nil {
+						return err
+					}
+				} else if fieldVal.Type().Kind() == sliceType {
 					eltType := fieldType.Type.Elem()
 					if err := c.decodeSlice(path, src, fieldVal, eltType); err != nil {
 						return err
@@ -109,23 +178,67 @@ func (c *Configuration) decodeSlice(path string, src *configurationValue,
 	dst reflect.Value, eltType reflect.Type) error {
 
 	srcVal := reflect.ValueOf(src.value)
-	if eltType == dateType {
+	if hasDecodeHook(eltType) {
+		if src.ctype&_ArrayType == 0 {
+			return newOptionError(ErrTypeMismatch, path,
+				"value of type %s is not assignable to type %s", src.ctype, dst.Type())
+		}
+		a := reflect.MakeSlice(dst.Type(), srcVal.Len(), srcVal.Len())
+		for i := 0; i < srcVal.Len(); i++ {
+			elt := &configurationValue{
+				ctype: src.ctype ^ _ArrayType,
+				value: srcVal.Index(i).Interface(),
+			}
+			eltPath := fmt.Sprintf("%s[%d]", path, i)
+			if err := c.decodeHook(eltPath, elt, a.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(a)
+	} else if eltType == dateType {
 		if src.ctype != _ArrayType|_DateType {
-			return fmt.Errorf(
-				"'%s': value of type %s is not assignable to type []time.Time",
-				path, src.ctype)
+			return newOptionError(ErrTypeMismatch, path,
+				"value of type %s is not assignable to type []time.Time", src.ctype)
 		}
 		a := []time.Time{}
 		for i := 0; i < srcVal.Len(); i++ {
 			a = append(a, srcVal.Index(i).Interface().(time.Time))
 		}
 		dst.Set(reflect.ValueOf(a))
+	} else if eltType == durationType {
+		if src.ctype&_ArrayType == 0 {
+			return newOptionError(ErrTypeMismatch, path,
+				"value of type %s is not assignable to type []time.Duration", src.ctype)
+		}
+		a := []time.Duration{}
+		for i := 0; i < srcVal.Len(); i++ {
+			elt := &configurationValue{
+				ctype: src.ctype ^ _ArrayType,
+				value: srcVal.Index(i).Interface(),
+			}
+			d, err := decodeDuration(fmt.Sprintf("%s[%d]", path, i), elt)
+			if err != nil {
+				return err
+			}
+			a = append(a, d)
+		}
+		dst.Set(reflect.ValueOf(a))
+	} else if eltType == sizeType {
+		if src.ctype != _ArrayType|_SizeType && src.ctype != _ArrayType|_IntType {
+			return newOptionError(ErrTypeMismatch, path,
+				"value of type %s is not assignable to type []config.Size", src.ctype)
+		}
+		a := []Size{}
+		for i := 0; i < srcVal.Len(); i++ {
+			a = append(a, Size(reflect.ValueOf(srcVal.Index(i).Interface()).Int()))
+		}
+		dst.Set(reflect.ValueOf(a))
 	} else {
 		switch eltType.Kind() {
 		case boolType:
 			if src.ctype != _ArrayType|_BoolType {
-				return fmt.Errorf("'%s': value of type %s is not assignable to type []bool",
-					path, src.ctype)
+				return newOptionError(ErrTypeMismatch, path,
+					"value of type %s is not assignable to type []bool", src.ctype)
 			}
 			a := []bool{}
 			for i := 0; i < srcVal.Len(); i++ {
@@ -133,8 +246,8 @@ func (c *Configuration) decodeSlice(path string, src *configurationValue,
 			}
 			dst.Set(reflect.ValueOf(a))
 		case intType:
-			if src.ctype != _ArrayType|_IntType {
-				return fmt.Errorf("'%s': value of type %s is not assignable to type []int64", path,
+			if src.ctype != _ArrayType|_IntType && src.ctype != _ArrayType|_SizeType {
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type []int64",
 					src.ctype)
 			}
 			a := []int64{}
@@ -144,7 +257,7 @@ func (c *Configuration) decodeSlice(path string, src *configurationValue,
 			dst.Set(reflect.ValueOf(a))
 		case floatType:
 			if src.ctype != _ArrayType|_FloatType {
-				return fmt.Errorf("'%s': value of type %s is not assignable to type []float64", path,
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type []float64",
 					src.ctype)
 			}
 			a := []float64{}
@@ -154,7 +267,7 @@ func (c *Configuration) decodeSlice(path string, src *configurationValue,
 			dst.Set(reflect.ValueOf(a))
 		case stringType:
 			if src.ctype != _ArrayType|_StringType {
-				return fmt.Errorf("'%s': value of type %s is not assignable to type []string", path,
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type []string",
 					src.ctype)
 			}
 			a := []string{}
@@ -166,7 +279,7 @@ func (c *Configuration) decodeSlice(path string, src *configurationValue,
 			// Type not supported, '[]float' for instance. Default case is
 			// unlikely to be executed since unsupported type errors are
 			// trapped while configuration files are loaded.
-			return fmt.Errorf("'%s': value of type %s is not assignable to type %s", path,
+			return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type %s",
 				dst.Kind(), src.ctype)
 		}
 
@@ -177,47 +290,159 @@ func (c *Configuration) decodeSlice(path string, src *configurationValue,
 func (c *Configuration) decodeValue(path string, src *configurationValue, dst reflect.Value) error {
 	if dst.Type() == dateType {
 		if src.ctype != _DateType {
-			return fmt.Errorf(
-				"'%s': value of type %s is not assignable to type time.Time", path,
+			return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type time.Time",
 				src.ctype)
 		}
 		dst.Set(reflect.ValueOf(src.value.(time.Time)))
+	} else if dst.Type() == durationType {
+		d, err := decodeDuration(path, src)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(d))
 	} else {
 		switch dst.Kind() {
 		case boolType:
 			if src.ctype != _BoolType {
-				return fmt.Errorf(
-					"'%s': value of type %s is not assignable to type bool", path,
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type bool",
 					src.ctype)
 			}
 			dst.SetBool(src.value.(bool))
 		case intType:
+			// Sizes are a number of bytes and may be stored in integers.
+			if src.ctype == _SizeType {
+				dst.SetInt(int64(src.value.(Size)))
+				break
+			}
 			if src.ctype != _IntType {
-				return fmt.Errorf(
-					"'%s': value of type %s is not assignable to type int64", path,
-					src.ctype)
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type %s",
+					src.ctype, dst.Type())
 			}
 			dst.SetInt(src.value.(int64))
 		case floatType:
 			if src.ctype != _FloatType {
-				return fmt.Errorf(
-					"'%s': value of type %s is not assignable to type float64", path,
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type float64",
 					src.ctype)
 			}
 			dst.SetFloat(src.value.(float64))
 		case stringType:
 			if src.ctype != _StringType {
-				return fmt.Errorf(
-					"'%s': value of type %s is not assignable to type string", path,
+				return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type string",
 					src.ctype)
 			}
 			dst.SetString(src.value.(string))
 		default:
 			// Type not supported, 'int' for instance.
-			return fmt.Errorf(
-				"'%s': value of type %s is not assignable to type %s", path,
+			return newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type %s",
 				dst.Kind(), src.ctype)
 		}
 	}
 	return nil
 }
+
+// decodeDuration accepts durations, strings in time.ParseDuration format for
+// compatibility with configurations written before duration literals were
+// introduced, and integers (nanoseconds).
+func decodeDuration(path string, src *configurationValue) (time.Duration, error) {
+	switch src.ctype {
+	case _DurationType:
+		return src.value.(time.Duration), nil
+	case _StringType:
+		d, err := time.ParseDuration(src.value.(string))
+		if err != nil {
+			return 0, newOptionError(ErrInvalidValue, path, "invalid duration %q", src.value)
+		}
+		return d, nil
+	case _IntType:
+		return time.Duration(src.value.(int64)), nil
+	}
+	return 0, newOptionError(ErrTypeMismatch, path, "value of type %s is not assignable to type time.Duration",
+		src.ctype)
+}
+
+// hasDecodeHook returns true if values of given type are decoded by a
+// registered decoder, an Unmarshaler or an encoding.TextUnmarshaler.
+func hasDecodeHook(typ reflect.Type) bool {
+	if lookupDecoder(typ) != nil {
+		return true
+	}
+	if typ.Kind() != ptrType {
+		typ = reflect.PtrTo(typ)
+	}
+	if typ.Implements(unmarshalerType) {
+		return true
+	}
+	// time.Time is a TextUnmarshaler but dates are handled natively.
+	return typ.Implements(textUnmarshalerType) && typ.Elem() != dateType
+}
+
+func (c *Configuration) decodeHook(path string, src *configurationValue, dst reflect.Value) error {
+	if fn := lookupDecoder(dst.Type()); fn != nil {
+		v, err := fn(path, src.value)
+		if err != nil {
+			return wrapOptionError(ErrInvalidValue, path, err)
+		}
+		rv := reflect.ValueOf(v)
+		if rv.IsValid() == false {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if rv.Type().AssignableTo(dst.Type()) == false {
+			return newOptionError(ErrTypeMismatch, path,
+				"decoder returned a value of type %s not assignable to type %s", rv.Type(), dst.Type())
+		}
+		dst.Set(rv)
+		return nil
+	}
+
+	// Methods may be declared with a pointer receiver; work on a pointer to
+	// the destination. Pointer fields are allocated and only set on success.
+	var ptr reflect.Value
+	if dst.Kind() == ptrType {
+		ptr = reflect.New(dst.Type().Elem())
+	} else {
+		ptr = dst.Addr()
+	}
+
+	if u, ok := ptr.Interface().(Unmarshaler); ok {
+		if err := u.UnmarshalConfig(path, src.value); err != nil {
+			return wrapOptionError(ErrInvalidValue, path, err)
+		}
+	} else {
+		text, ok := valueToText(src)
+		if ok == false {
+			return newOptionError(ErrTypeMismatch, path,
+				"value of type %s is not assignable to type %s", src.ctype, dst.Type())
+		}
+		u := ptr.Interface().(encoding.TextUnmarshaler)
+		if err := u.UnmarshalText([]byte(text)); err != nil {
+			return wrapOptionError(ErrInvalidValue, path, err)
+		}
+	}
+	if dst.Kind() == ptrType {
+		dst.Set(ptr)
+	}
+	return nil
+}
+
+// valueToText returns the textual form of a scalar value, as expected by
+// encoding.TextUnmarshaler implementations. Strings are returned verbatim.
+func valueToText(src *configurationValue) (string, bool) {
+	switch src.ctype {
+	case _BoolType:
+		return strconv.FormatBool(src.value.(bool)), true
+	case _IntType:
+		return strconv.FormatInt(src.value.(int64), 10), true
+	case _FloatType:
+		return strconv.FormatFloat(src.value.(float64), 'g', -1, 64), true
+	case _DateType:
+		return src.value.(time.Time).Format(time.RFC3339), true
+	case _StringType:
+		return src.value.(string), true
+	case _DurationType:
+		return src.value.(time.Duration).String(), true
+	case _SizeType:
+		return src.value.(Size).String(), true
+	}
+	return "", false
+}
@@ -1,8 +1,15 @@
 package config_test
 
 import (
+	"fmt"
 	"github.com/cbonello/gp-config"
 	. "launchpad.net/gocheck"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
 	"time"
 )
 
@@ -2675,3 +2682,281 @@ func (ct *DecodeTests) TestDecodeStringArray9(c *C) {
 	c.Check(err, ErrorMatches,
 		"'String': value of type \\[\\]time.Time is not assignable to type \\[\\]string")
 }
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	case "error":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown log level %q", text)
+	}
+	return nil
+}
+
+type port uint16
+
+func (p *port) UnmarshalConfig(path string, value interface{}) error {
+	i, ok := value.(int64)
+	if ok == false || i < 1 || i > 65535 {
+		return fmt.Errorf("invalid port number %v", value)
+	}
+	*p = port(i)
+	return nil
+}
+
+// Decode(): encoding.TextUnmarshaler fields.
+func (ct *DecodeTests) TestDecodeHook1(c *C) {
+	contents := `
+[server]
+	ip = "192.168.0.1"
+	level = "info"
+	pattern = "^foo[0-9]+$"
+	big = "123456789012345678901234567890"
+	small = 42
+	hosts = ["10.0.0.1", "10.0.0.2"]`
+
+	type (
+		values struct {
+			IP      net.IP
+			Level   logLevel
+			Pattern *regexp.Regexp
+			Big     big.Int
+			Small   *big.Int
+			Hosts   []net.IP
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("server", &v)
+	c.Assert(err, IsNil)
+	c.Check(v.IP.String(), Equals, "192.168.0.1")
+	c.Check(v.Level, Equals, logLevel(1))
+	c.Check(v.Pattern.MatchString("foo12"), Equals, true)
+	c.Check(v.Big.String(), Equals, "123456789012345678901234567890")
+	c.Check(v.Small.Int64(), Equals, int64(42))
+	c.Check(len(v.Hosts), Equals, 2)
+	c.Check(v.Hosts[1].String(), Equals, "10.0.0.2")
+}
+
+// Decode(): encoding.TextUnmarshaler error.
+func (ct *DecodeTests) TestDecodeHook2(c *C) {
+	contents := `level = "verbose"`
+
+	type (
+		values struct {
+			Level logLevel
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("", &v)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "'Level': unknown log level \"verbose\"")
+}
+
+// Decode(): config.Unmarshaler fields.
+func (ct *DecodeTests) TestDecodeHook3(c *C) {
+	contents := `
+[server]
+	port = 8080
+	ports = [80, 70000]`
+
+	type (
+		values struct {
+			Port  port
+			Ports []port
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("server", &v)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "'server.Ports\\[1\\]': invalid port number 70000")
+	c.Check(v.Port, Equals, port(8080))
+}
+
+// Decode(): built-in *url.URL decoder.
+func (ct *DecodeTests) TestDecodeHook4(c *C) {
+	contents := `url = "https://example.com:8443/api"`
+
+	type (
+		values struct {
+			URL *url.URL
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("", &v)
+	c.Assert(err, IsNil)
+	c.Check(v.URL.Host, Equals, "example.com:8443")
+	c.Check(v.URL.Path, Equals, "/api")
+}
+
+// Decode(): registered decoder.
+func (ct *DecodeTests) TestDecodeHook5(c *C) {
+	contents := `mode = 420`
+
+	type (
+		values struct {
+			Mode os.FileMode
+		}
+	)
+
+	typ := reflect.TypeOf(os.FileMode(0))
+	config.RegisterDecoder(typ, func(path string, value interface{}) (interface{}, error) {
+		i, ok := value.(int64)
+		if ok == false {
+			return nil, fmt.Errorf("not a file mode")
+		}
+		return os.FileMode(i), nil
+	})
+	defer config.RegisterDecoder(typ, nil)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("", &v)
+	c.Assert(err, IsNil)
+	c.Check(v.Mode, Equals, os.FileMode(0644))
+}
+
+// Decode(): registered decoder returning a value of wrong type.
+func (ct *DecodeTests) TestDecodeHook6(c *C) {
+	contents := `mode = 420`
+
+	type (
+		values struct {
+			Mode os.FileMode
+		}
+	)
+
+	typ := reflect.TypeOf(os.FileMode(0))
+	config.RegisterDecoder(typ, func(path string, value interface{}) (interface{}, error) {
+		return "rw-r--r--", nil
+	})
+	defer config.RegisterDecoder(typ, nil)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("", &v)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches,
+		"'Mode': decoder returned a value of type string not assignable to type fs.FileMode")
+}
+
+// Decode(): durations and sizes.
+func (ct *DecodeTests) TestDecodeDuration1(c *C) {
+	contents := `
+[server]
+	timeout = 1m30s
+	legacy = "250ms"
+	retries = [1s, 2s]
+	legacies = ["1s", "1m"]
+	max_body = 10MiB
+	raw = 64KiB
+	buffers = [1KiB, 2KiB]`
+
+	type (
+		values struct {
+			Timeout  time.Duration
+			Legacy   time.Duration
+			Retries  []time.Duration
+			Legacies []time.Duration
+			MaxBody  config.Size `option:"max_body"`
+			Raw      int64
+			Buffers  []config.Size
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("server", &v)
+	c.Assert(err, IsNil)
+	c.Check(v.Timeout, Equals, 90*time.Second)
+	c.Check(v.Legacy, Equals, 250*time.Millisecond)
+	c.Check(v.Retries, EqualSlice, []time.Duration{time.Second, 2 * time.Second})
+	c.Check(v.Legacies, EqualSlice, []time.Duration{time.Second, time.Minute})
+	c.Check(v.MaxBody, Equals, config.Size(10<<20))
+	c.Check(v.Raw, Equals, int64(64<<10))
+	c.Check(v.Buffers, EqualSlice, []config.Size{1024, 2048})
+}
+
+// Decode(): malformed duration string.
+func (ct *DecodeTests) TestDecodeDuration2(c *C) {
+	contents := `timeout = "soon"`
+
+	type (
+		values struct {
+			Timeout time.Duration
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("", &v)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "'Timeout': invalid duration \"soon\"")
+}
+
+// Decode(): wrong duration type.
+func (ct *DecodeTests) TestDecodeDuration3(c *C) {
+	contents := `timeout = 1.5`
+
+	type (
+		values struct {
+			Timeout time.Duration
+		}
+	)
+
+	ct.config = config.NewConfiguration()
+	err0 := ct.config.LoadString(contents)
+	c.Check(err0, IsNil)
+	defer ct.cleanTestEnv(c)
+
+	v := values{}
+	err := ct.config.Decode("", &v)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches,
+		"'Timeout': value of type float64 is not assignable to type time.Duration")
+}
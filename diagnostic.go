@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used by colored diagnostics.
+const (
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[1;31m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// Diagnostic formats an error as file:line:col: message, followed by the
+// offending line of input with the erroneous text underlined, and a hint if
+// one is available:
+//
+//	app.cfg:3:5: 'b': unexpected ''' character
+//	    b = 'single quotes'
+//	        ^
+//	    hint: strings must be double-quoted
+//
+// ANSI escape sequences are used to highlight the output if colored is true.
+func (c *ConfigurationError) Diagnostic(colored bool) string {
+	if c == nil {
+		return ""
+	}
+	style := func(code, s string) string {
+		if colored {
+			return code + s + ansiReset
+		}
+		return s
+	}
+
+	pos := c.Filename
+	if c.Line > 0 {
+		pos = fmt.Sprintf("%s:%d:%d", c.Filename, c.Line, c.Column)
+	}
+	buf := style(ansiBold, pos+":") + " " + style(ansiRed, c.msg) + "\n"
+	if c.Line > 0 && c.source != "" {
+		buf += "    " + c.source + "\n"
+		buf += "    " + c.marker(style) + "\n"
+	}
+	if c.Hint != "" {
+		buf += "    " + style(ansiCyan, "hint: "+c.Hint) + "\n"
+	}
+	return buf
+}
+
+// marker returns a line underlining the offending text of the source line.
+// Tabs are preserved so that the marker lines up with the source.
+func (c *ConfigurationError) marker(style func(code, s string) string) string {
+	start := c.Column - 1
+	if start > len(c.source) {
+		start = len(c.source)
+	}
+	end := start + 1
+	if c.EndLine == c.Line && c.EndColumn > c.Column {
+		end = c.EndColumn - 1
+	}
+	if end > len(c.source) {
+		end = len(c.source)
+	}
+
+	indent := ""
+	for _, r := range c.source[:start] {
+		if r == '\t' {
+			indent += "\t"
+		} else {
+			indent += " "
+		}
+	}
+	width := utf8.RuneCountInString(c.source[start:end])
+	if width < 1 {
+		width = 1
+	}
+	return indent + style(ansiRed, "^"+strings.Repeat("~", width-1))
+}
+
+// Diagnostic formats all errors of the list; see
+// ConfigurationError.Diagnostic.
+func (l ErrorList) Diagnostic(colored bool) string {
+	buf := ""
+	for _, err := range l {
+		buf += err.Diagnostic(colored)
+	}
+	return buf
+}
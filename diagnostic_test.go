@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+)
+
+type (
+	DiagnosticTests struct{}
+)
+
+var (
+	_ = Suite(&DiagnosticTests{})
+)
+
+// Diagnostic(): caret under a single character and hint.
+func (dt *DiagnosticTests) TestDiagnostic1(c *C) {
+	contents := "a = 1\n\tb = 'single quotes'\n"
+
+	cfg := config.NewConfiguration()
+	var err *config.ConfigurationError
+	c.Assert(errors.As(cfg.LoadString(contents), &err), Equals, true)
+	c.Check(err.Line, Equals, 2)
+	c.Check(err.Column, Equals, 6)
+	c.Check(err.EndLine, Equals, 2)
+	c.Check(err.EndColumn, Equals, 7)
+	c.Check(err.Diagnostic(false), Equals, ":string::2:6: 'b': unexpected ''' character\n"+
+		"    \tb = 'single quotes'\n"+
+		"    \t    ^\n"+
+		"    hint: strings must be double-quoted\n")
+}
+
+// Diagnostic(): underline spanning a token.
+func (dt *DiagnosticTests) TestDiagnostic2(c *C) {
+	contents := "size = 99999999999999999999 # bytes"
+
+	cfg := config.NewConfiguration()
+	var err *config.ConfigurationError
+	c.Assert(errors.As(cfg.LoadString(contents), &err), Equals, true)
+	c.Check(err.Column, Equals, 8)
+	c.Check(err.EndColumn, Equals, 28)
+	c.Check(err.Diagnostic(false), Equals,
+		":string::1:8: 'size': integer constant 99999999999999999999 overflows int64\n"+
+			"    size = 99999999999999999999 # bytes\n"+
+			"           ^~~~~~~~~~~~~~~~~~~~\n")
+}
+
+// Diagnostic(): colored output.
+func (dt *DiagnosticTests) TestDiagnostic3(c *C) {
+	contents := "foo = [1, \"two\"]"
+
+	cfg := config.NewConfiguration()
+	var err *config.ConfigurationError
+	c.Assert(errors.As(cfg.LoadString(contents), &err), Equals, true)
+	c.Check(err.Diagnostic(true), Equals,
+		"\x1b[1m:string::1:11:\x1b[0m \x1b[1;31mcannot use type string as type int64\x1b[0m\n"+
+			"    foo = [1, \"two\"]\n"+
+			"              \x1b[1;31m^~~~~\x1b[0m\n"+
+			"    \x1b[36mhint: array elements must share the same type\x1b[0m\n")
+}
+
+// Diagnostic(): errors without position.
+func (dt *DiagnosticTests) TestDiagnostic4(c *C) {
+	var err *config.ConfigurationError
+	c.Check(err.Diagnostic(false), Equals, "")
+
+	err = &config.ConfigurationError{Filename: "app.cfg"}
+	c.Check(err.Diagnostic(false), Equals, "app.cfg: \n")
+}
+
+// Diagnostic(): error lists.
+func (dt *DiagnosticTests) TestDiagnostic5(c *C) {
+	contents := "[a]\n[b]\nc = \n"
+
+	p := config.NewStringParser(contents)
+	errs := p.ParseAll(config.NewConfiguration(), false)
+	c.Assert(errs, HasLen, 2)
+	c.Check(errs.Diagnostic(false), Equals, ":string::1:3: empty section a\n"+
+		"    [a]\n"+
+		"      ^\n"+
+		"    hint: sections must declare at least one option\n"+
+		":string::3:5: unexpected end-of-line\n"+
+		"    c = \n"+
+		"        ^\n"+
+		"    hint: value of option 'b.c' is missing\n")
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+type (
+	// ChangeKind identifies the kind of a change reported by Diff.
+	ChangeKind int
+
+	// Change describes an option that differs between two configurations.
+	// Values of secrets are replaced by their SecretRef or encrypted form.
+	Change struct {
+		Kind   ChangeKind
+		Option string      // Path of option, as first declared.
+		Old    interface{} // Value in first configuration; nil if added.
+		New    interface{} // Value in second configuration; nil if removed.
+	}
+)
+
+// Kinds of changes.
+const (
+	Added   ChangeKind = iota + 1 // Option only defined in second configuration.
+	Removed                       // Option only defined in first configuration.
+	Changed                       // Option whose value or type differs.
+)
+
+// Diff returns the options added, removed and changed by b with respect to
+// a, sorted by path. Values are compared with their type: 1 and 1.0 differ.
+// Options are matched as by a; that is, case-insensitively unless
+// SetCaseSensitive(true) was called on a. Formatting and declaration order
+// of options are ignored. A nil configuration is compared as an empty one.
+func Diff(a, b *Configuration) []Change {
+	// Each configuration is copied under its own lock; holding both locks
+	// could deadlock with a concurrent Diff(b, a).
+	a, b = a.snapshot(), b.snapshot()
+
+	changes := []Change{}
+	bOptions := make(map[string]configurationValue, len(b.options))
+	for _, v := range b.options {
+		bOptions[a.key(v.name)] = v
+	}
+	for key, old := range a.options {
+		if v, found := bOptions[key]; found == false {
+			changes = append(changes, Change{Kind: Removed, Option: old.name, Old: old.redacted()})
+		} else if old.ctype != v.ctype || old.masked != v.masked || equalValues(old.value, v.value) == false {
+			changes = append(changes, Change{Kind: Changed, Option: old.name, Old: old.redacted(), New: v.redacted()})
+		}
+		delete(bOptions, key)
+	}
+	for _, v := range bOptions {
+		changes = append(changes, Change{Kind: Added, Option: v.name, New: v.redacted()})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return a.key(changes[i].Option) < a.key(changes[j].Option)
+	})
+	return changes
+}
+
+// snapshot returns a copy of the configuration, or an empty configuration
+// if c is nil.
+func (c *Configuration) snapshot() *Configuration {
+	if c == nil {
+		return NewConfiguration()
+	}
+	return c.clone()
+}
+
+// equalValues returns true if given values of same type are equal. NaN is
+// equal to itself.
+func equalValues(x, y interface{}) bool {
+	rx, ry := reflect.ValueOf(x), reflect.ValueOf(y)
+	if rx.Kind() == sliceType {
+		if rx.Len() != ry.Len() {
+			return false
+		}
+		for i := 0; i < rx.Len(); i++ {
+			if equalValues(rx.Index(i).Interface(), ry.Index(i).Interface()) == false {
+				return false
+			}
+		}
+		return true
+	}
+	if rx.Kind() == floatType && math.IsNaN(rx.Float()) && math.IsNaN(ry.Float()) {
+		return true
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// String returns the name of the kind of change.
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// String dumps a change to a string: "+ option = value" for added options,
+// "- option = value" for removed ones and "~ option = old -> new" for
+// changed ones.
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s = %s", c.Option, formatValue(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s = %s", c.Option, formatValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s = %s -> %s", c.Option, formatValue(c.Old), formatValue(c.New))
+}
@@ -0,0 +1,126 @@
+package config_test
+
+import (
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	DiffTests struct{}
+)
+
+var (
+	_ = Suite(&DiffTests{})
+)
+
+// Diff(): added, removed and changed options.
+func (dt *DiffTests) TestDiff1(c *C) {
+	a, b := config.NewConfiguration(), config.NewConfiguration()
+	c.Assert(a.LoadString(`version = [1, 0]
+[server]
+	host = "localhost"
+	port = 80
+	debug = false
+	ratio = 1
+	notanumber = nan
+["example.com"]
+	root = "/var/www"`), IsNil)
+	// Declaration order, case and formatting are ignored.
+	c.Assert(b.LoadString(`version = [1,
+	1]
+[SERVER]
+	ratio = 1.0
+	Port = 8080
+	notanumber = nan
+	host="localhost"
+	timeout = 30s
+[server]
+	debug = false
+["example.com"]
+	root = "/var/www"`), IsNil)
+
+	changes := config.Diff(a, b)
+	c.Assert(changes, HasLen, 4)
+	c.Check(changes[0], DeepEquals, config.Change{Kind: config.Changed,
+		Option: "server.port", Old: int64(80), New: int64(8080)})
+	c.Check(changes[1], DeepEquals, config.Change{Kind: config.Changed,
+		Option: "server.ratio", Old: int64(1), New: 1.0})
+	c.Check(changes[2], DeepEquals, config.Change{Kind: config.Added,
+		Option: "SERVER.timeout", New: 30 * time.Second})
+	c.Check(changes[3], DeepEquals, config.Change{Kind: config.Changed,
+		Option: "version", Old: []int64{1, 0}, New: []int64{1, 1}})
+
+	changes = config.Diff(b, a)
+	c.Assert(changes, HasLen, 4)
+	c.Check(changes[2].Kind, Equals, config.Removed)
+	c.Check(changes[2].Old, Not(IsNil))
+	c.Check(changes[2].New, IsNil)
+
+	c.Check(config.Diff(a, a), HasLen, 0)
+	c.Check(math.IsNaN(a.GetFloatDefault("server.notanumber", 0)), Equals, true)
+}
+
+// Diff(): case sensitivity.
+func (dt *DiffTests) TestDiff2(c *C) {
+	a, b := config.NewConfiguration(), config.NewConfiguration()
+	a.SetCaseSensitive(true)
+	c.Assert(a.LoadString("a = 1"), IsNil)
+	c.Assert(b.LoadString("A = 1"), IsNil)
+	changes := config.Diff(a, b)
+	c.Assert(changes, HasLen, 2)
+	c.Check(changes[0].Kind, Equals, config.Added)
+	c.Check(changes[1].Kind, Equals, config.Removed)
+	c.Check(config.Diff(b, a), HasLen, 0)
+}
+
+// Diff(): nil and concurrently modified configurations.
+func (dt *DiffTests) TestDiff3(c *C) {
+	a, b := config.NewConfiguration(), config.NewConfiguration()
+	c.Assert(b.LoadString("a = 1"), IsNil)
+	c.Check(config.Diff(nil, nil), HasLen, 0)
+	changes := config.Diff(nil, b)
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Kind, Equals, config.Added)
+	changes = config.Diff(b, nil)
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Kind, Equals, config.Removed)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				config.Diff(a, b)
+				a.Set("a", int64(j))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				config.Diff(b, a)
+				b.Set("a", int64(j))
+			}
+		}()
+	}
+	wg.Wait()
+	c.Check(config.Diff(a, b), HasLen, 0)
+}
+
+// Change.String().
+func (dt *DiffTests) TestChangeString1(c *C) {
+	a, b := config.NewConfiguration(), config.NewConfiguration()
+	c.Assert(a.LoadString("a = 1\nb = true\nc = [\"x\"]"), IsNil)
+	c.Assert(b.LoadString("a = 2\nc = [\"x\", \"y\"]\nd = 10MiB"), IsNil)
+	changes := []string{}
+	for _, change := range config.Diff(a, b) {
+		changes = append(changes, change.String())
+	}
+	c.Check(changes, EqualSlice, []string{`~ a = 1 -> 2`, `- b = true`,
+		`~ c = ["x"] -> ["x", "y"]`, `+ d = 10MiB`})
+	c.Check(config.Added.String(), Equals, "added")
+	c.Check(config.ChangeKind(0).String(), Equals, "ChangeKind(0)")
+}
@@ -7,7 +7,8 @@
 //
 // Main differences are:
 //
-// * section and option names are case insensitive;
+// * section and option names are case insensitive by default (see
+//   Configuration.SetCaseSensitive);
 // * multi-dimensional arrays are not supported;
 // * tables are not supported; and
 // * array of tables are not supported.
@@ -18,11 +19,26 @@
 // Parser implements following grammar (EBNF style):
 //
 // 	  config = section | options
-// 	  section = '[' IDENTIFIER ']' EOL options
+// 	  section = '[' key ']' EOL options
 // 	  options = option {option}
-// 	  option =  IDENTIFIER '=' (value | array) EOL
-//    value = BOOL | INT | FLOAT | DATE | STRING
+// 	  option =  key '=' (value | array) EOL
+//    value = BOOL | INT | FLOAT | DATE | STRING | DURATION | SIZE
 //    array = '[' {EOF} value {EOF} {, {EOF} value {EOF} } ']'
+//    key = IDENTIFIER | STRING
+//
+// Quoted keys may contain any character, dots included (e.g.
+// ["example.com"]). In paths given to Get, HasOption, Options and Decode,
+// such names must be quoted as well (e.g. "example.com".root); JoinPath
+// builds these paths.
+//
+// INT may be written in decimal, hexadecimal (0xFF), octal (0o640) or
+// binary (0b1010); leading zeros do not denote an octal constant. Digits of
+// INT and FLOAT may be separated by underscores (1_000_000). FLOAT also
+// accepts inf, +inf, -inf and nan.
+//
+// DURATION follows time.ParseDuration syntax (e.g. 1m30s) and SIZE is a
+// number followed by one of B, kB, MB, GB, TB, PB, KiB, MiB, GiB, TiB or PiB
+// (e.g. 10MiB, 1.5GiB); sizes must be a whole number of bytes.
 //
 // 2. Usage
 //
@@ -31,6 +47,7 @@
 // Configuration can either be stored in a string or a file.
 //
 //    import (
+//        "errors"
 //        "flag"
 //        "fmt"
 //        "github.com/cbonello/gp-config"
@@ -51,26 +68,81 @@
 //        // Set default options (Production mode).
 //        cfg := config.NewConfiguration()
 //        if err := cfg.LoadString(deflt); err != nil {
-//            fmt.Printf("error: default config: %d:%d: %s\n",
-//            err.Line, err.Column, err)
+//            var cerr *config.ConfigurationError
+//            if errors.As(err, &cerr) {
+//                fmt.Print(cerr.Diagnostic(false))
+//            }
 //            os.Exit(1)
 //        }
 //        if dev {
 //            // Override default options with debug mode settings.
 //            if err := cfg.LoadFile("debug.cfg"); err != nil {
-//                fmt.Printf("error: %s: %d:%d: %s\n",
-//                err.Filename, err.Line, err.Column, err)
+//                var cerr *config.ConfigurationError
+//                if errors.As(err, &cerr) {
+//                    fmt.Print(cerr.Diagnostic(false))
+//                }
 //                os.Exit(1)
 //            }
 //        }
 //        ...
 //    }
 //
+// Diagnostic formats an error as file:line:col: message followed by the
+// offending line with the erroneous text underlined, and a hint when one is
+// available.
+//
 // Contents of `debug.cfg` may for instance be:
 //
 //    [database]
 //        dbname = "mydb_test"
 //
+// A missing file is an error (code ErrNotFound). Load accepts options to make
+// a file optional or to look it up in a list of directories, and reports
+// which file was loaded:
+//
+//    res, err := cfg.Load("app.cfg", &config.LoadOptions{
+//        SearchPaths: config.DefaultSearchPaths("app"),
+//    })
+//    if err == nil {
+//        fmt.Println("configuration loaded from", res.Path)
+//    }
+//
+// Configurations may also be read from an io.Reader with LoadReader or from
+// a file system (embed.FS, fstest.MapFS, ...) with LoadFS.
+// LoadStream parses very large inputs as they are read, with memory use
+// bounded by the longest token.
+//
+// Profile sections, named section@profile, override the options of their
+// base section when their profile is active; see SetProfile. The active
+// profile may also be given by environment variable GPCONFIG_PROFILE, and
+// Origin reports which declaration supplied a value. Secrets of inactive
+// profiles are not resolved.
+//
+//    [database]
+//        host = "db.example.com"
+//    [database@dev]
+//        host = "localhost"
+//
+// Secrets are strings resolved when a configuration is loaded, from a file,
+// an environment variable or any source registered with
+// RegisterSecretResolver. String, Diff and exports write their reference
+// instead of their value, even if the option is overridden by Set, an
+// environment variable or a flag; SetResolveSecrets(false) keeps them
+// unresolved.
+//
+//    [database]
+//        password = secret("file:/run/secrets/db_password")
+//        token = secret("env:DB_TOKEN")
+//
+// Strings starting with "enc:v1:", encrypted with AES-GCM by Encrypt or
+// "gpconfig encrypt", are decrypted when a configuration is loaded with the
+// key supplied by SetKeyProvider; loading fails without a key unless secrets
+// are not resolved. Like secrets, they are written encrypted by String, Diff
+// and exports.
+//
+//    [database]
+//        password = "enc:v1:Zm9vYmFy..."
+//
 // 2.2. Reading Configuration Files
 //
 // 2.2.1. Basic API
@@ -105,6 +177,99 @@
 //            os.Exit(1)
 //        }
 //
+// Fields whose type implements encoding.TextUnmarshaler (net.IP, big.Int,
+// ...) or Unmarshaler decode themselves. Decoders for third-party types may
+// be registered with RegisterDecoder.
+//
+// 2.3. Errors
+//
+// Errors carry a stable code that may be tested with errors.Is: ErrIO,
+// ErrSyntax, ErrEmptySection, ErrTypeMismatch, ErrUnknownOption,
+// ErrUnknownSection, ErrInvalidArgument, ErrInvalidValue, ErrNotFound,
+// ErrUnsupportedFormat and ErrSecret.
+//
+//    port, err := cfg.GetInt("server.port")
+//    if errors.Is(err, config.ErrUnknownOption) {
+//        port = 80
+//    }
+//
+// Load errors are *ConfigurationError values, getter and Decode errors are
+// *OptionError values. When a file cannot be read, the error unwraps to the
+// error reported by the operating system.
+//
+// 2.4. Comparing Configurations
+//
+// Diff returns the options added, removed and changed by a configuration
+// with respect to another one, with their typed values. Formatting and
+// declaration order are ignored.
+//
+//    for _, change := range config.Diff(production, debug) {
+//        fmt.Println(change) // ~ server.port = 80 -> 8080
+//    }
+//
+// 2.5. Exporting Configurations
+//
+// ExportJSON, ExportYAML and ExportEnv write a configuration in JSON, YAML
+// and dotenv (KEY=value) formats. Sections are written as nested objects.
+// Integers are written without fraction and floating-point numbers with a
+// fraction or an exponent (1.0); dates are written in RFC 3339 format,
+// durations and sizes as strings in literal form ("1m30s", "10MiB"). See
+// ExportJSON for details.
+//
+// 2.6. Importing Configurations
+//
+// LoadJSON and LoadINI load legacy JSON and INI files into a configuration.
+// Top-level JSON objects and INI sections become sections; types are
+// inferred from values (80 is an integer, "1m30s" a duration, ...). Options
+// override the ones already loaded, so that JSON and INI files may be
+// layered with native ones, and are decoded with Decode.
+//
+//    err := cfg.LoadJSON("legacy.json", r)
+//
+// 2.7. File Formats
+//
+// Formats implement the Format interface and are registered by name and
+// filename extension with RegisterFormat; "cfg" (native), "json", "ini",
+// "yaml" and "env" are provided, the last two for writing only. LoadFile
+// chooses the format of a file by extension, so that LoadFile("app.json")
+// reads a JSON file; files whose extension is not registered are read in
+// native format. LoadFormat reads a configuration in a format selected by
+// name and Export writes one.
+//
+//    err := cfg.Export(os.Stdout, "yaml")
+//
+// 2.8. Configuration Sources
+//
+// A Source supplies options to a configuration: a file (NewFileSource), a
+// string (NewStringSource), environment variables (NewEnvSource), command
+// line flags (NewFlagSource), a document fetched from an HTTP server
+// (NewHTTPSource) or any type implementing the Source interface, such as a
+// client of a key-value service. AddSource loads sources by
+// increasing precedence; Reload loads them again and Watch reloads the
+// configuration whenever a source reports a change.
+//
+//    cfg.AddSource(config.NewFileSource("app.cfg", nil))
+//    cfg.AddSource(config.NewEnvSource("app")) // APP_SERVER_PORT=8080
+//
+// HTTPSource honors ETag and Cache-Control headers, validates documents
+// before using them and falls back to a local copy of the last valid
+// document when the server is unavailable.
+//
+// 2.9. Tooling
+//
+// Package github.com/cbonello/gp-config/scanner exposes the tokenizer used
+// by the parser. Parser.ParseAST returns the syntax tree of a configuration,
+// comments included, as defined by package github.com/cbonello/gp-config/ast;
+// linters, formatters and editors may so work on the same grammar as the
+// runtime parser. NewLexer and the Tk* constants are deprecated in favor of
+// package scanner.
+//
+// Package github.com/cbonello/gp-config/format formats configurations in
+// canonical form; it is used by "gpconfig fmt", a gofmt-like command
+// provided by github.com/cbonello/gp-config/cmd/gpconfig. Package
+// github.com/cbonello/gp-config/lint reports suspicious constructs (options
+// declared twice, plaintext secrets, ...); it is used by "gpconfig lint".
+//
 // 3. Examples
 //
 // Demo applications are provided in the `examples/` directory. To launch
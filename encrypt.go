@@ -0,0 +1,184 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+type (
+	// KeyProvider supplies the key of encrypted values; see
+	// SetKeyProvider.
+	KeyProvider interface {
+		// Key returns an AES key of 16, 24 or 32 bytes.
+		Key() ([]byte, error)
+	}
+
+	// KeyProviderFunc adapts a function to the KeyProvider interface.
+	KeyProviderFunc func() ([]byte, error)
+)
+
+// EncryptedPrefix starts encrypted values; it is followed by the base64
+// encoding of the nonce and of the AES-GCM sealed value.
+const EncryptedPrefix = "enc:v1:"
+
+// SetKeyProvider sets the provider of the key used to decrypt values
+// encrypted by Encrypt. String values starting with EncryptedPrefix are
+// decrypted when configurations are loaded; loading them fails with code
+// ErrSecret if no provider is set, unless secrets are not resolved (see
+// SetResolveSecrets) in which case they are kept encrypted. Decrypted values
+// are secrets: String, Diff and exports write their encrypted form (see
+// IsSecret).
+//
+//	cfg.SetKeyProvider(config.KeyFromEnv("APP_KEY"))
+//	cfg.LoadFile("app.cfg") // password = "enc:v1:..."
+//
+// Only configurations loaded afterwards are affected.
+func (c *Configuration) SetKeyProvider(keys KeyProvider) {
+	if c != nil {
+		c.Lock()
+		defer c.Unlock()
+		c.keys = keys
+	}
+}
+
+// Key calls f().
+func (f KeyProviderFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// StaticKey returns a provider of given key.
+func StaticKey(key []byte) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		return key, nil
+	})
+}
+
+// KeyFromEnv returns a provider of the key stored in base64 in given
+// environment variable.
+func KeyFromEnv(name string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		s, found := os.LookupEnv(name)
+		if found == false {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		return ParseKey(s)
+	})
+}
+
+// KeyFromFile returns a provider of the key stored in base64 in given file.
+func KeyFromFile(filename string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return ParseKey(string(contents))
+	})
+}
+
+// GenerateKey returns a random 256-bit key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParseKey decodes a key written in base64, as by FormatKey. Surrounding
+// white space is ignored.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.New("malformed key; expected base64")
+	}
+	if n := len(key); n != 16 && n != 24 && n != 32 {
+		return nil, fmt.Errorf("invalid key size %d; expected 16, 24 or 32 bytes", n)
+	}
+	return key, nil
+}
+
+// FormatKey encodes a key in base64.
+func FormatKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// Encrypt encrypts s with AES-GCM and given key, and returns a value that
+// may be stored in configuration files as a string:
+//
+//	password = "enc:v1:..."
+func Encrypt(s string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(s), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the value encrypted by Encrypt with given key.
+func Decrypt(s string, key []byte) (string, error) {
+	if strings.HasPrefix(s, EncryptedPrefix) == false {
+		return "", fmt.Errorf("encrypted values must start with %q", EncryptedPrefix)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(s[len(EncryptedPrefix):])
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce := sealed[:gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, sealed[len(nonce):], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value; wrong key or corrupted value")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if n := len(key); n != 16 && n != 24 && n != 32 {
+		return nil, fmt.Errorf("invalid key size %d; expected 16, 24 or 32 bytes", n)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncrypted returns true if s is an encrypted value.
+func isEncrypted(s string) bool {
+	return strings.HasPrefix(s, EncryptedPrefix)
+}
+
+// decrypt returns the value of given option encrypted in s, or s itself if
+// secrets are not resolved or if option will be ignored (see resolves).
+func (c *Configuration) decrypt(option, s string) (string, error) {
+	if c.resolves(option) == false {
+		return s, nil
+	}
+	c.RLock()
+	keys := c.keys
+	c.RUnlock()
+	if keys == nil {
+		return "", errors.New("no key to decrypt value; see SetKeyProvider")
+	}
+	key, err := keys.Key()
+	if err != nil {
+		return "", fmt.Errorf("cannot get key: %s", err)
+	}
+	return Decrypt(s, key)
+}
@@ -0,0 +1,106 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"strings"
+)
+
+type EncryptTests struct{}
+
+var (
+	_ = Suite(&EncryptTests{})
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// Encrypt(), Decrypt(), SetKeyProvider().
+func (et *EncryptTests) TestEncrypt1(c *C) {
+	encrypted, err := config.Encrypt("s3cr3t", testKey)
+	c.Assert(err, IsNil)
+	c.Check(strings.HasPrefix(encrypted, config.EncryptedPrefix), Equals, true)
+	plain, err := config.Decrypt(encrypted, testKey)
+	c.Check(err, IsNil)
+	c.Check(plain, Equals, "s3cr3t")
+
+	contents := "[database]\nuser = \"admin\"\npassword = \"" + encrypted + "\"\n"
+	// Encrypted values are kept encrypted only on request.
+	cfg := config.NewConfiguration()
+	err = cfg.LoadString(contents)
+	c.Check(errors.Is(err, config.ErrSecret), Equals, true)
+	c.Check(err, ErrorMatches, "'database.password': no key to decrypt value; see SetKeyProvider")
+	cfg.SetResolveSecrets(false)
+	c.Assert(cfg.LoadString(contents), IsNil)
+	c.Check(cfg.GetStringDefault("database.password", ""), Equals, encrypted)
+	c.Check(cfg.IsSecret("database.password"), Equals, true)
+
+	cfg = config.NewConfiguration()
+	cfg.SetKeyProvider(config.StaticKey(testKey))
+	c.Assert(cfg.LoadString(contents), IsNil)
+	c.Check(cfg.GetStringDefault("database.password", ""), Equals, "s3cr3t")
+	c.Check(cfg.IsSecret("database.password"), Equals, true)
+	c.Check(cfg.String(), Equals, "database.user = \"admin\"\ndatabase.password = \""+encrypted+"\"\n")
+
+	for _, format := range []string{"cfg", "ini", "json"} {
+		var buf bytes.Buffer
+		c.Assert(cfg.Export(&buf, format), IsNil)
+		c.Check(strings.Contains(buf.String(), "s3cr3t"), Equals, false, Commentf(format))
+		dst := config.NewConfiguration()
+		dst.SetKeyProvider(config.StaticKey(testKey))
+		c.Assert(dst.LoadFormat(format, "app", &buf), IsNil)
+		c.Check(config.Diff(cfg, dst), HasLen, 0, Commentf(format))
+		c.Check(dst.GetStringDefault("database.password", ""), Equals, "s3cr3t")
+	}
+
+	// Profile sections.
+	cfg = config.NewConfiguration()
+	cfg.SetKeyProvider(config.StaticKey(testKey))
+	cfg.SetProfile("prod")
+	c.Assert(cfg.LoadString("[database@prod]\npassword = \""+encrypted+"\""), IsNil)
+	c.Check(cfg.GetStringDefault("database.password", ""), Equals, "s3cr3t")
+}
+
+// Errors.
+func (et *EncryptTests) TestEncrypt2(c *C) {
+	wrongKey := []byte("fedcba9876543210")
+	encrypted, err := config.Encrypt("s3cr3t", wrongKey)
+	c.Assert(err, IsNil)
+	tests := []struct {
+		contents string
+		msg      string
+		line     int
+		column   int
+		code     config.ErrorCode
+	}{
+		{"a = 1\nb = \"" + encrypted + "\"", "'b': cannot decrypt value; wrong key or corrupted value", 2, 5, config.ErrSecret},
+		{"a = \"enc:v1:???\"", "'a': malformed encrypted value", 1, 5, config.ErrSecret},
+		{"a = [\"x\", \"" + encrypted + "\"]", "'a': encrypted values cannot be array elements", 1, 11, config.ErrInvalidValue},
+	}
+	for _, test := range tests {
+		cfg := config.NewConfiguration()
+		cfg.SetKeyProvider(config.StaticKey(testKey))
+		var err *config.ConfigurationError
+		c.Assert(errors.As(cfg.LoadString(test.contents), &err), Equals, true, Commentf(test.contents))
+		c.Check(err.Error(), Equals, test.msg, Commentf(test.contents))
+		c.Check(err.Line, Equals, test.line, Commentf(test.contents))
+		c.Check(err.Column, Equals, test.column, Commentf(test.contents))
+		c.Check(err.Code, Equals, test.code, Commentf(test.contents))
+	}
+
+	cfg := config.NewConfiguration()
+	cfg.SetKeyProvider(config.KeyFromEnv("GPCONFIG_TEST_UNSET"))
+	lerr := cfg.LoadJSON("app.json", strings.NewReader(`{"a": "`+encrypted+`"}`))
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(lerr, &cerr), Equals, true)
+	c.Check(cerr.Error(), Equals, "'a': cannot get key: environment variable GPCONFIG_TEST_UNSET is not set")
+	c.Check(cerr.Line, Equals, 1)
+	c.Check(cerr.Column, Equals, 7)
+	c.Check(errors.Is(lerr, config.ErrSecret), Equals, true)
+
+	_, err = config.Encrypt("s3cr3t", []byte("short"))
+	c.Check(err, ErrorMatches, "invalid key size 5; expected 16, 24 or 32 bytes")
+	_, err = config.ParseKey("not base64")
+	c.Check(err, ErrorMatches, "malformed key; expected base64")
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+)
+
+type (
+	// ErrorCode identifies the kind of an error. Codes are stable and may be
+	// compared with errors.Is:
+	//
+	//	if errors.Is(err, config.ErrUnknownOption) {
+	//	    ...
+	//	}
+	ErrorCode int
+
+	// OptionError records errors reported while reading or decoding options.
+	OptionError struct {
+		Code   ErrorCode // Kind of error.
+		Option string    // Path of option; empty for invalid arguments.
+		msg    string
+		err    error // Underlying error, if any.
+	}
+)
+
+// Error codes.
+const (
+	// ErrIO flags a configuration that cannot be read.
+	ErrIO ErrorCode = 1
+	// ErrSyntax flags a lexical or syntax error.
+	ErrSyntax ErrorCode = 2
+	// ErrEmptySection flags a section without options.
+	ErrEmptySection ErrorCode = 3
+	// ErrTypeMismatch flags a value whose type does not match the expected
+	// one.
+	ErrTypeMismatch ErrorCode = 4
+	// ErrUnknownOption flags an undefined option.
+	ErrUnknownOption ErrorCode = 5
+	// ErrUnknownSection flags an undefined section.
+	ErrUnknownSection ErrorCode = 6
+	// ErrInvalidArgument flags an invalid argument given to a function.
+	ErrInvalidArgument ErrorCode = 7
+	// ErrInvalidValue flags a value rejected by a decoder.
+	ErrInvalidValue ErrorCode = 8
+	// ErrNotFound flags a required configuration file that does not exist.
+	ErrNotFound ErrorCode = 9
+	// ErrUnsupportedFormat flags a format that is not registered, or that
+	// cannot read or write a configuration.
+	ErrUnsupportedFormat ErrorCode = 10
+	// ErrSecret flags a secret that cannot be resolved or a value that cannot
+	// be decrypted.
+	ErrSecret ErrorCode = 11
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrIO:                "read error",
+	ErrSyntax:            "syntax error",
+	ErrEmptySection:      "empty section",
+	ErrTypeMismatch:      "type mismatch",
+	ErrUnknownOption:     "unknown option",
+	ErrUnknownSection:    "unknown section",
+	ErrInvalidArgument:   "invalid argument",
+	ErrInvalidValue:      "invalid value",
+	ErrNotFound:          "file not found",
+	ErrUnsupportedFormat: "unsupported format",
+	ErrSecret:            "unresolved secret",
+}
+
+// Error returns a description of the error code.
+func (e ErrorCode) Error() string {
+	if name, found := errorCodeNames[e]; found {
+		return name
+	}
+	return fmt.Sprintf("error code %d", int(e))
+}
+
+func newOptionError(code ErrorCode, option string, format string, args ...interface{}) *OptionError {
+	return &OptionError{
+		Code:   code,
+		Option: option,
+		msg:    fmt.Sprintf("'%s': %s", option, fmt.Sprintf(format, args...)),
+	}
+}
+
+// newArgumentError returns an error unrelated to an option.
+func newArgumentError(format string, args ...interface{}) *OptionError {
+	return &OptionError{
+		Code: ErrInvalidArgument,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// wrapOptionError returns an error wrapping err, as reported by a decoder
+// for given option.
+func wrapOptionError(code ErrorCode, option string, err error) *OptionError {
+	return &OptionError{
+		Code:   code,
+		Option: option,
+		msg:    fmt.Sprintf("'%s': %s", option, err),
+		err:    err,
+	}
+}
+
+func unknownOptionError(option string) *OptionError {
+	return newOptionError(ErrUnknownOption, option, "unknown option")
+}
+
+// Error dumps an option error to a string.
+func (e *OptionError) Error() string {
+	return e.msg
+}
+
+// Is returns true if target is the code of the error.
+func (e *OptionError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == e.Code
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *OptionError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true if target is the code of the error.
+func (c *ConfigurationError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == c.Code
+}
+
+// Unwrap returns the underlying error (e.g. an *os.PathError), if any.
+func (c *ConfigurationError) Unwrap() error {
+	return c.err
+}
+
+// Unwrap returns the errors of the list so that errors.Is and errors.As
+// inspect all of them.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, err := range l {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,151 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/cbonello/gp-config"
+	"io/ioutil"
+	. "launchpad.net/gocheck"
+	"os"
+)
+
+type (
+	ErrorsTests struct{}
+)
+
+var (
+	_ = Suite(&ErrorsTests{})
+)
+
+// ErrorCode.Error().
+func (et *ErrorsTests) TestErrorCode1(c *C) {
+	c.Check(config.ErrSyntax.Error(), Equals, "syntax error")
+	c.Check(config.ErrUnknownOption.Error(), Equals, "unknown option")
+	c.Check(config.ErrorCode(1000).Error(), Equals, "error code 1000")
+	// Codes are part of the API and must not change.
+	c.Check(int(config.ErrIO), Equals, 1)
+	c.Check(int(config.ErrInvalidValue), Equals, 8)
+	c.Check(int(config.ErrUnsupportedFormat), Equals, 10)
+	c.Check(int(config.ErrSecret), Equals, 11)
+}
+
+// LoadString(): error codes of load errors.
+func (et *ErrorsTests) TestLoadErrors1(c *C) {
+	tests := []struct {
+		contents string
+		code     config.ErrorCode
+	}{
+		{"a = ", config.ErrSyntax},
+		{"a = 'b'", config.ErrSyntax},
+		{"[empty]\n[full]\n\ta = 1", config.ErrEmptySection},
+		{"a = [1, true]", config.ErrTypeMismatch},
+	}
+
+	for _, t := range tests {
+		cfg := config.NewConfiguration()
+		var err *config.ConfigurationError
+		c.Assert(errors.As(cfg.LoadString(t.contents), &err), Equals, true)
+		c.Check(err.Code, Equals, t.code)
+		c.Check(errors.Is(err, t.code), Equals, true)
+		c.Check(errors.Is(err, config.ErrIO), Equals, false)
+	}
+}
+
+// LoadFile(): read errors unwrap to the operating system error.
+func (et *ErrorsTests) TestLoadErrors2(c *C) {
+	dir, err0 := ioutil.TempDir("", "ErrorsTest")
+	c.Assert(err0, IsNil)
+	defer os.Remove(dir)
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadFile(dir)
+	c.Assert(err, NotNil)
+	c.Check(errors.Is(err, config.ErrIO), Equals, true)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Filename, Equals, dir)
+	var perr *os.PathError
+	c.Check(errors.As(err, &perr), Equals, true)
+}
+
+// LoadFile(): syntax errors are reported as *ConfigurationError.
+func (et *ErrorsTests) TestLoadErrors3(c *C) {
+	f, err0 := ioutil.TempFile("", "ErrorsTest")
+	c.Assert(err0, IsNil)
+	defer os.Remove(f.Name())
+	_, err0 = f.Write([]byte("[values"))
+	c.Assert(err0, IsNil)
+	f.Close()
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadFile(f.Name())
+	c.Assert(err, NotNil)
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Line, Equals, 1)
+	c.Check(errors.Unwrap(err), IsNil)
+}
+
+// ParseAll(): error lists match codes of their errors.
+func (et *ErrorsTests) TestLoadErrors4(c *C) {
+	p := config.NewStringParser("a = 'b'\n[empty]\n[full]\n\tc = 1")
+	errs := p.ParseAll(config.NewConfiguration(), false)
+	c.Assert(errs, HasLen, 2)
+	err := errs.Err()
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+	c.Check(errors.Is(err, config.ErrEmptySection), Equals, true)
+	c.Check(errors.Is(err, config.ErrTypeMismatch), Equals, false)
+}
+
+// Get*(): unknown options and type mismatches.
+func (et *ErrorsTests) TestGetErrors1(c *C) {
+	cfg := config.NewConfiguration()
+	err0 := cfg.LoadString("[server]\n\tport = 8080")
+	c.Assert(err0, IsNil)
+
+	_, err := cfg.GetInt("server.host")
+	c.Check(err, ErrorMatches, "'server.host': unknown option")
+	c.Check(errors.Is(err, config.ErrUnknownOption), Equals, true)
+	c.Check(errors.Is(err, config.ErrTypeMismatch), Equals, false)
+
+	_, err = cfg.GetString("server.port")
+	c.Check(err, ErrorMatches, "'server.port': not a string")
+	c.Check(errors.Is(err, config.ErrTypeMismatch), Equals, true)
+	var oerr *config.OptionError
+	c.Assert(errors.As(err, &oerr), Equals, true)
+	c.Check(oerr.Option, Equals, "server.port")
+	c.Check(oerr.Code, Equals, config.ErrTypeMismatch)
+}
+
+// Decode(): error codes.
+func (et *ErrorsTests) TestDecodeErrors1(c *C) {
+	type (
+		server struct {
+			Port string
+		}
+		ports struct {
+			Port port
+		}
+	)
+
+	cfg := config.NewConfiguration()
+	err0 := cfg.LoadString("[server]\n\tport = 70000")
+	c.Assert(err0, IsNil)
+
+	err := cfg.Decode("client", &server{})
+	c.Check(err, ErrorMatches, "'client': unknown section")
+	c.Check(errors.Is(err, config.ErrUnknownSection), Equals, true)
+
+	err = cfg.Decode("server", server{})
+	c.Check(err, ErrorMatches, "structure argument is not a pointer")
+	c.Check(errors.Is(err, config.ErrInvalidArgument), Equals, true)
+
+	err = cfg.Decode("server", &server{})
+	c.Check(errors.Is(err, config.ErrTypeMismatch), Equals, true)
+
+	// Errors reported by decoders are wrapped.
+	err = cfg.Decode("server", &ports{})
+	c.Check(err, ErrorMatches, "'server.Port': invalid port number 70000")
+	c.Check(errors.Is(err, config.ErrInvalidValue), Equals, true)
+	c.Check(errors.Unwrap(err), ErrorMatches, "invalid port number 70000")
+}
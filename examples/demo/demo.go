@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/cbonello/gp-config"
 	"math/rand"
@@ -31,8 +32,10 @@ func main() {
 	// Set default options (Production mode).
 	cfg := config.NewConfiguration()
 	if err := cfg.LoadString(deflt); err != nil {
-		fmt.Printf("error: default config: %d:%d: %s\n",
-			err.Line, err.Column, err)
+		var cerr *config.ConfigurationError
+		if errors.As(err, &cerr) {
+			fmt.Print(cerr.Diagnostic(false))
+		}
 		os.Exit(1)
 	}
 
@@ -42,8 +45,12 @@ func main() {
 		fmt.Println("DEBUG MODE")
 		// Override default options with debug mode settings.
 		if err := cfg.LoadFile("debug.cfg"); err != nil {
-			fmt.Printf("error: %s: %d:%d: %s\n",
-				err.Filename, err.Line, err.Column, err)
+			var cerr *config.ConfigurationError
+			if errors.As(err, &cerr) {
+				fmt.Print(cerr.Diagnostic(false))
+			} else {
+				fmt.Println("error:", err)
+			}
 			os.Exit(1)
 		}
 	} else {
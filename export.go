@@ -0,0 +1,358 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	// exportSection lists the options of a section in declaration order.
+	exportSection struct {
+		name    string // Empty for options declared before first section.
+		options []exportOption
+	}
+
+	exportOption struct {
+		name  string
+		value interface{}
+	}
+)
+
+var (
+	// YAML keys that need not be quoted.
+	yamlPlainKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	// YAML plain scalars read as booleans or null.
+	yamlReserved = map[string]bool{
+		"y": true, "yes": true, "n": true, "no": true, "true": true,
+		"false": true, "on": true, "off": true, "null": true, "~": true,
+	}
+)
+
+// tree returns the options of the configuration grouped by section.
+// Sections and options are sorted in declaration order and spelled as first
+// declared.
+func (c *Configuration) tree() ([]exportSection, error) {
+	c.RLock()
+	defer c.RUnlock()
+	values := make([]configurationValue, 0, len(c.options))
+	for _, v := range c.options {
+		values = append(values, v)
+	}
+	sort.Sort(bySeq(values))
+
+	tree := []exportSection{{}}
+	index := map[string]int{}
+	for _, v := range values {
+		names, err := SplitPath(v.name)
+		if err != nil {
+			return nil, err
+		}
+		section, option := "", names[0]
+		if len(names) > 1 {
+			section, option = names[0], names[1]
+		}
+		i, found := index[c.key(JoinPath(section))]
+		if section != "" && found == false {
+			i = len(tree)
+			index[c.key(JoinPath(section))] = i
+			tree = append(tree, exportSection{name: section})
+		}
+		tree[i].options = append(tree[i].options, exportOption{option, v.redacted()})
+	}
+	return tree, nil
+}
+
+// exportTree is similar to tree but returns an error if an option declared
+// before the first section has the name of a section, since both cannot be
+// exported as keys of the same object.
+func (c *Configuration) exportTree() ([]exportSection, error) {
+	tree, err := c.tree()
+	if err != nil {
+		return nil, err
+	}
+	c.RLock()
+	defer c.RUnlock()
+	for _, o := range tree[0].options {
+		for _, s := range tree[1:] {
+			if c.key(JoinPath(o.name)) == c.key(JoinPath(s.name)) {
+				return nil, newOptionError(ErrInvalidValue, o.name,
+					"option and section share the same name")
+			}
+		}
+	}
+	return tree, nil
+}
+
+// ExportJSON writes the configuration to w as a JSON object. Options
+// declared before the first section are members of the object and sections
+// are nested objects. Types are mapped as follows:
+//
+//	bool            true or false
+//	int64           number without fraction nor exponent (80)
+//	float64         number with a fraction or an exponent (1.0, 1e+21);
+//	                inf, -inf and nan are written as strings
+//	time.Time       RFC 3339 string ("1979-05-27T07:32:00Z")
+//	string          string
+//	time.Duration   string in literal form ("1m30s")
+//	Size            string in literal form ("10MiB")
+//	secrets         string holding the reference ("secret(\"env:DB_TOKEN\")")
+//	arrays          arrays
+//
+// Members are written in declaration order.
+func (c *Configuration) ExportJSON(w io.Writer) error {
+	tree, err := c.exportTree()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	members := func(options []exportOption, indent string) {
+		for i, o := range options {
+			if i > 0 {
+				bw.WriteString(",")
+			}
+			fmt.Fprintf(bw, "\n%s%s: %s", indent, jsonString(o.name), jsonValue(o.value))
+		}
+	}
+
+	bw.WriteString("{")
+	members(tree[0].options, "  ")
+	for i, s := range tree[1:] {
+		if i > 0 || len(tree[0].options) > 0 {
+			bw.WriteString(",")
+		}
+		fmt.Fprintf(bw, "\n  %s: {", jsonString(s.name))
+		members(s.options, "    ")
+		bw.WriteString("\n  }")
+	}
+	if len(tree) > 1 || len(tree[0].options) > 0 {
+		bw.WriteString("\n")
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// ExportYAML writes the configuration to w as a YAML mapping, organized as
+// by ExportJSON. Types are mapped as follows:
+//
+//	bool            true or false
+//	int64           integer (80)
+//	float64         floating-point number with a fraction or an exponent
+//	                (1.0, 1e+21), .inf, -.inf or .nan
+//	time.Time       timestamp (1979-05-27T07:32:00Z)
+//	string          double-quoted string
+//	time.Duration   double-quoted string in literal form ("1m30s")
+//	Size            double-quoted string in literal form ("10MiB")
+//	secrets         double-quoted string holding the reference
+//	arrays          flow sequences ([1, 2])
+func (c *Configuration) ExportYAML(w io.Writer) error {
+	tree, err := c.exportTree()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, o := range tree[0].options {
+		fmt.Fprintf(bw, "%s: %s\n", yamlKey(o.name), yamlValue(o.value))
+	}
+	for _, s := range tree[1:] {
+		fmt.Fprintf(bw, "%s:\n", yamlKey(s.name))
+		for _, o := range s.options {
+			fmt.Fprintf(bw, "  %s: %s\n", yamlKey(o.name), yamlValue(o.value))
+		}
+	}
+	return bw.Flush()
+}
+
+// ExportEnv writes the configuration to w as KEY=value lines (dotenv
+// format). Keys are made of given prefix, the section name and the option
+// name separated by underscores, in upper case; characters other than
+// letters and digits are replaced by underscores (server.max_body is
+// written as PREFIX_SERVER_MAX_BODY). Strings are double-quoted, other
+// values are written in literal form (80, 1.0, 1979-05-27T07:32:00Z, 1m30s,
+// 10MiB) and array elements are separated by commas. Secrets are written as
+// double-quoted references.
+func (c *Configuration) ExportEnv(w io.Writer, prefix string) error {
+	tree, err := c.exportTree()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, s := range tree {
+		for _, o := range s.options {
+			fmt.Fprintf(bw, "%s=%s\n", EnvKey(prefix, s.name, o.name), envValue(o.value))
+		}
+	}
+	return bw.Flush()
+}
+
+// EnvKey returns the environment variable name of an option, as written by
+// ExportEnv. Empty names are skipped.
+func EnvKey(names ...string) string {
+	parts := []string{}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		parts = append(parts, strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return unicode.ToUpper(r)
+			}
+			return '_'
+		}, name))
+	}
+	return strings.Join(parts, "_")
+}
+
+// exportValue calls f for each element of an array, or for value itself.
+// It returns true if value is an array.
+func exportValue(value interface{}, f func(v interface{})) bool {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != sliceType {
+		f(value)
+		return false
+	}
+	for i := 0; i < rv.Len(); i++ {
+		f(rv.Index(i).Interface())
+	}
+	return true
+}
+
+// JSONValue returns the JSON encoding of an option value as written by
+// ExportJSON: durations and sizes are strings in literal form ("1m30s",
+// "10MiB"), floating-point numbers always have a fraction or an exponent and
+// infinite or NaN ones are strings ("inf", "nan").
+func JSONValue(value interface{}) json.RawMessage {
+	return json.RawMessage(jsonValue(value))
+}
+
+func jsonValue(value interface{}) string {
+	elements := []string{}
+	isArray := exportValue(value, func(v interface{}) {
+		var s string
+		switch v := v.(type) {
+		case bool:
+			s = strconv.FormatBool(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = formatFloat(v)
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				s = jsonString(s)
+			}
+		case time.Time:
+			s = jsonString(v.Format(time.RFC3339))
+		case string:
+			s = jsonString(v)
+		case fmt.Stringer:
+			// time.Duration and Size.
+			s = jsonString(v.String())
+		}
+		elements = append(elements, s)
+	})
+	if isArray {
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return elements[0]
+}
+
+func yamlValue(value interface{}) string {
+	elements := []string{}
+	isArray := exportValue(value, func(v interface{}) {
+		var s string
+		switch v := v.(type) {
+		case bool:
+			s = strconv.FormatBool(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			switch {
+			case math.IsInf(v, 1):
+				s = ".inf"
+			case math.IsInf(v, -1):
+				s = "-.inf"
+			case math.IsNaN(v):
+				s = ".nan"
+			default:
+				s = formatFloat(v)
+			}
+		case time.Time:
+			s = v.Format(time.RFC3339)
+		case string:
+			s = jsonString(v)
+		case fmt.Stringer:
+			s = jsonString(v.String())
+		}
+		elements = append(elements, s)
+	})
+	if isArray {
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return elements[0]
+}
+
+func envValue(value interface{}) string {
+	elements := []string{}
+	isArray := exportValue(value, func(v interface{}) {
+		switch v := v.(type) {
+		case float64:
+			elements = append(elements, formatFloat(v))
+		case time.Time:
+			elements = append(elements, v.Format(time.RFC3339))
+		default:
+			elements = append(elements, fmt.Sprint(v))
+		}
+	})
+	s := strings.Join(elements, ",")
+	switch value.(type) {
+	case string, SecretRef:
+		return jsonString(s)
+	}
+	if isArray && reflect.TypeOf(value).Elem().Kind() == stringType {
+		return jsonString(s)
+	}
+	return s
+}
+
+func yamlKey(name string) string {
+	if yamlPlainKeyRe.MatchString(name) && yamlReserved[strings.ToLower(name)] == false {
+		return name
+	}
+	return jsonString(name)
+}
+
+// formatFloat formats a floating-point number so that it is not read back
+// as an integer: 1 is written as 1.0.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.ContainsAny(s, ".e") == false {
+		s += ".0"
+	}
+	return s
+}
+
+// jsonString returns s as a JSON string. HTML characters are not escaped.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,151 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"math"
+	"time"
+)
+
+type (
+	ExportTests struct{}
+)
+
+var (
+	_ = Suite(&ExportTests{})
+)
+
+const exported = `version = [1, 0]
+ratio = 1
+[server]
+	host = "<localhost>"
+	port = 80
+	weight = 2.5
+	created = 1979-05-27T07:32:00Z
+	timeout = 1m30s
+	max_body = 10MiB
+	limits = [inf, -inf, nan]
+	"yes" = true
+["example.com"]
+	hosts = ["a", "b"]
+[Server]
+	max-conn = 100`
+
+// ExportJSON().
+func (et *ExportTests) TestExportJSON1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(exported), IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.ExportJSON(&buf), IsNil)
+	c.Check(buf.String(), Equals, `{
+  "version": [1, 0],
+  "ratio": 1,
+  "server": {
+    "host": "<localhost>",
+    "port": 80,
+    "weight": 2.5,
+    "created": "1979-05-27T07:32:00Z",
+    "timeout": "1m30s",
+    "max_body": "10MiB",
+    "limits": ["inf", "-inf", "nan"],
+    "yes": true,
+    "max-conn": 100
+  },
+  "example.com": {
+    "hosts": ["a", "b"]
+  }
+}
+`)
+
+	// Floating-point numbers have a fraction.
+	cfg = config.NewConfiguration()
+	c.Assert(cfg.LoadString("a = [1.0, 2]\nb = 1.0e21"), IsNil)
+	buf.Reset()
+	c.Assert(cfg.ExportJSON(&buf), IsNil)
+	c.Check(buf.String(), Equals, "{\n  \"a\": [1.0, 2.0],\n  \"b\": 1e+21\n}\n")
+
+	buf.Reset()
+	c.Assert(config.NewConfiguration().ExportJSON(&buf), IsNil)
+	c.Check(buf.String(), Equals, "{}\n")
+}
+
+// JSONValue().
+func (et *ExportTests) TestJSONValue1(c *C) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{int64(8080), "8080"},
+		{2.0, "2.0"},
+		{math.Inf(-1), `"-inf"`},
+		{90 * time.Second, `"1m30s"`},
+		{config.Size(10 << 20), `"10MiB"`},
+		{[]float64{1, math.NaN()}, `[1.0, "nan"]`},
+		{"a\"b", `"a\"b"`},
+	}
+	for _, test := range tests {
+		c.Check(string(config.JSONValue(test.value)), Equals, test.expected)
+	}
+}
+
+// ExportYAML().
+func (et *ExportTests) TestExportYAML1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(exported), IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.ExportYAML(&buf), IsNil)
+	c.Check(buf.String(), Equals, `version: [1, 0]
+ratio: 1
+server:
+  host: "<localhost>"
+  port: 80
+  weight: 2.5
+  created: 1979-05-27T07:32:00Z
+  timeout: "1m30s"
+  max_body: "10MiB"
+  limits: [.inf, -.inf, .nan]
+  "yes": true
+  max-conn: 100
+"example.com":
+  hosts: ["a", "b"]
+`)
+}
+
+// ExportEnv().
+func (et *ExportTests) TestExportEnv1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(exported), IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.ExportEnv(&buf, "app"), IsNil)
+	c.Check(buf.String(), Equals, `APP_VERSION=1,0
+APP_RATIO=1
+APP_SERVER_HOST="<localhost>"
+APP_SERVER_PORT=80
+APP_SERVER_WEIGHT=2.5
+APP_SERVER_CREATED=1979-05-27T07:32:00Z
+APP_SERVER_TIMEOUT=1m30s
+APP_SERVER_MAX_BODY=10MiB
+APP_SERVER_LIMITS=inf,-inf,nan
+APP_SERVER_YES=true
+APP_SERVER_MAX_CONN=100
+APP_EXAMPLE_COM_HOSTS="a,b"
+`)
+
+	c.Check(config.EnvKey("", "server", "max-body"), Equals, "SERVER_MAX_BODY")
+	c.Check(config.EnvKey("", "", "été"), Equals, "_T_")
+}
+
+// Export*(): options and sections sharing a name.
+func (et *ExportTests) TestExport1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString("server = 1\n[Server]\n\tport = 80"), IsNil)
+	var buf bytes.Buffer
+	err := cfg.ExportJSON(&buf)
+	c.Check(err, ErrorMatches, "'server': option and section share the same name")
+	c.Check(errors.Is(err, config.ErrInvalidValue), Equals, true)
+	c.Check(cfg.ExportYAML(&buf), NotNil)
+	c.Check(cfg.ExportEnv(&buf, ""), NotNil)
+	c.Check(buf.Len(), Equals, 0)
+}
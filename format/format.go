@@ -0,0 +1,240 @@
+// Package format implements the canonical formatting of gp-config
+// configuration files:
+//
+//   - options declared in sections are indented with a tab;
+//   - '=' is surrounded by a single space;
+//   - sections are preceded by a blank line and consecutive blank lines are
+//     merged;
+//   - arrays are written on one line unless they were declared on several
+//     lines, hold comments or are too long, in which case each element is
+//     written on its own line;
+//   - numbers and dates are written in canonical form (5. -> 5.0,
+//     1.5E+3 -> 1.5e+3, +1 -> 1, 1979-05-27T07:32:00Z, ...).
+//
+// Comments are preserved. Formatting is idempotent.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"github.com/cbonello/gp-config/ast"
+	"github.com/cbonello/gp-config/scanner"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Arrays whose line would be longer are wrapped.
+	maxLineLength = 80
+	// Width of a tab when computing line lengths.
+	tabWidth = 8
+)
+
+type printer struct {
+	buf      bytes.Buffer
+	lastLine int // Last source line printed, 0 if none.
+}
+
+// Source formats given configuration. Filename is used in errors, which are
+// *config.ConfigurationError values.
+func Source(filename string, src []byte) ([]byte, error) {
+	p, err := config.NewReaderParser(filename, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	f, cerr := p.ParseAST()
+	if cerr != nil {
+		return nil, cerr
+	}
+	var buf bytes.Buffer
+	if err := Node(&buf, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Node writes the canonical form of given tree to w.
+func Node(w io.Writer, f *ast.File) error {
+	p := &printer{}
+	for _, o := range f.Options {
+		p.option(o, "")
+	}
+	for _, s := range f.Sections {
+		p.section(s)
+	}
+	if f.Footer != nil {
+		for _, c := range f.Footer.List {
+			indent := ""
+			if len(f.Sections) > 0 && c.Hash.Column > 1 {
+				// Indented comments belong to last section.
+				indent = "\t"
+			}
+			p.comments(&ast.CommentGroup{List: []*ast.Comment{c}}, indent)
+		}
+	}
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// space writes a blank line if given source line is not adjacent to the last
+// line printed, or if forced.
+func (p *printer) space(line int, force bool) {
+	if p.lastLine > 0 && (force || line > p.lastLine+1) {
+		p.buf.WriteByte('\n')
+	}
+}
+
+func (p *printer) comments(g *ast.CommentGroup, indent string) {
+	for _, c := range g.List {
+		p.space(c.Hash.Line, false)
+		fmt.Fprintf(&p.buf, "%s%s\n", indent, c.Text)
+		p.lastLine = c.Hash.Line
+	}
+}
+
+func (p *printer) section(s *ast.Section) {
+	// Sections are always preceded by a blank line.
+	if s.Doc != nil {
+		p.space(s.Doc.Pos().Line, true)
+		// The blank line is written; comments must not add another one.
+		p.lastLine = s.Doc.Pos().Line - 1
+		p.comments(s.Doc, "")
+		p.space(s.Lbrack.Line, false)
+	} else {
+		p.space(s.Lbrack.Line, true)
+	}
+	fmt.Fprintf(&p.buf, "[%s]", s.Name.Raw)
+	p.lineComment(s.Comment)
+	p.lastLine = s.Rbrack.Line
+	for i, o := range s.Options {
+		if i == 0 {
+			// No blank line after section header.
+			p.lastLine = o.Pos().Line - 1
+			if o.Doc != nil {
+				p.lastLine = o.Doc.Pos().Line - 1
+			}
+		}
+		p.option(o, "\t")
+	}
+}
+
+func (p *printer) option(o *ast.Option, indent string) {
+	if o.Doc != nil {
+		p.comments(o.Doc, indent)
+	}
+	p.space(o.Pos().Line, false)
+	prefix := fmt.Sprintf("%s%s = ", indent, o.Key.Raw)
+	p.buf.WriteString(prefix)
+	switch v := o.Value.(type) {
+	case *ast.Literal:
+		p.buf.WriteString(Literal(v))
+	case *ast.Array:
+		p.array(v, indent, len(prefix)+len(indent)*(tabWidth-1))
+	}
+	p.lineComment(o.Comment)
+	p.lastLine = o.End().Line
+}
+
+func (p *printer) lineComment(c *ast.Comment) {
+	if c != nil {
+		fmt.Fprintf(&p.buf, " %s", c.Text)
+	}
+	p.buf.WriteByte('\n')
+}
+
+// array writes given array; column is the width of the line preceding '['.
+func (p *printer) array(a *ast.Array, indent string, column int) {
+	elements := make([]string, len(a.Elements))
+	width := column + 2
+	for i, e := range a.Elements {
+		elements[i] = Literal(e)
+		width += len(elements[i]) + 2
+	}
+	if a.Lbrack.Line == a.Rbrack.Line && len(a.Comments) == 0 && width-2 <= maxLineLength {
+		p.buf.WriteString("[" + strings.Join(elements, ", ") + "]")
+		return
+	}
+
+	// One element per line. Comments declared on a line of an element
+	// follow it; others are written on their own lines.
+	comments := a.Comments
+	nextComment := func(line int) *ast.Comment {
+		if len(comments) > 0 && comments[0].Hash.Line <= line {
+			c := comments[0]
+			comments = comments[1:]
+			return c
+		}
+		return nil
+	}
+	p.buf.WriteByte('[')
+	if c := nextComment(a.Lbrack.Line); c != nil {
+		fmt.Fprintf(&p.buf, " %s", c.Text)
+	}
+	p.buf.WriteByte('\n')
+	for i, e := range a.Elements {
+		for c := nextComment(e.Pos().Line - 1); c != nil; c = nextComment(e.Pos().Line - 1) {
+			fmt.Fprintf(&p.buf, "%s\t%s\n", indent, c.Text)
+		}
+		p.buf.WriteString(indent + "\t" + elements[i])
+		if i < len(elements)-1 {
+			p.buf.WriteByte(',')
+		}
+		if c := nextComment(e.End().Line); c != nil {
+			fmt.Fprintf(&p.buf, " %s", c.Text)
+		}
+		p.buf.WriteByte('\n')
+	}
+	for c := nextComment(a.Rbrack.Line); c != nil; c = nextComment(a.Rbrack.Line) {
+		fmt.Fprintf(&p.buf, "%s\t%s\n", indent, c.Text)
+	}
+	p.buf.WriteString(indent + "]")
+}
+
+// Literal returns the canonical form of given literal.
+func Literal(l *ast.Literal) string {
+	raw := strings.TrimPrefix(l.Raw, "+")
+	switch l.Kind {
+	case scanner.TkInt:
+		sign, digits := "", raw
+		if strings.HasPrefix(raw, "-") {
+			sign, digits = "-", raw[1:]
+		}
+		if len(digits) > 1 && digits[0] == '0' && strings.ContainsRune("xob", rune(digits[1]|0x20)) {
+			// Lower case base prefix.
+			return sign + "0" + string(digits[1]|0x20) + digits[2:]
+		}
+		if strings.ContainsRune(digits, '_') == false {
+			return strconv.FormatInt(l.Value.(int64), 10)
+		}
+	case scanner.TkFloat:
+		return canonicalFloat(raw)
+	case scanner.TkDate:
+		return l.Value.(time.Time).UTC().Format(time.RFC3339)
+	}
+	return raw
+}
+
+func canonicalFloat(raw string) string {
+	sign := ""
+	if strings.HasPrefix(raw, "-") {
+		sign, raw = "-", raw[1:]
+	}
+	if raw == "inf" || raw == "nan" {
+		return sign + raw
+	}
+	mantissa, exponent := raw, ""
+	if i := strings.IndexAny(raw, "eE"); i >= 0 {
+		mantissa, exponent = raw[:i], "e"+raw[i+1:]
+	}
+	if strings.HasSuffix(mantissa, ".") {
+		mantissa += "0"
+	}
+	// Leading zeros of integer part.
+	for len(mantissa) > 1 && mantissa[0] == '0' && mantissa[1] != '.' {
+		mantissa = mantissa[1:]
+	}
+	return sign + mantissa + exponent
+}
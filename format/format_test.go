@@ -0,0 +1,214 @@
+package format_test
+
+import (
+	"errors"
+	"github.com/cbonello/gp-config"
+	"github.com/cbonello/gp-config/format"
+	. "launchpad.net/gocheck"
+)
+
+type (
+	FormatTests struct{}
+)
+
+var (
+	_ = Suite(&FormatTests{})
+)
+
+// Source(): indentation, spacing and blank lines.
+func (ft *FormatTests) TestSource1(c *C) {
+	src := `
+
+version=1
+name   =  "app"
+[server]
+
+
+port=80
+
+
+   host ="localhost"
+["example.com"]
+root="/var/www"
+`
+	expected := `version = 1
+name = "app"
+
+[server]
+	port = 80
+
+	host = "localhost"
+
+["example.com"]
+	root = "/var/www"
+`
+	res, err := format.Source("app.cfg", []byte(src))
+	c.Assert(err, IsNil)
+	c.Check(string(res), Equals, expected)
+}
+
+// Source(): comments.
+func (ft *FormatTests) TestSource2(c *C) {
+	src := `# Application settings.
+
+# Version.
+version = [1, 0] # Major, minor.
+# Server.
+[server]   # Web server.
+# Port.
+port = 80
+hosts = [ # Hosts.
+# Primary.
+"a", # First.
+"b"
+# Last.
+] # Done.
+	# debug = true
+
+# EOF`
+	expected := `# Application settings.
+
+# Version.
+version = [1, 0] # Major, minor.
+
+# Server.
+[server] # Web server.
+	# Port.
+	port = 80
+	hosts = [ # Hosts.
+		# Primary.
+		"a", # First.
+		"b"
+		# Last.
+	] # Done.
+	# debug = true
+
+# EOF
+`
+	res, err := format.Source("app.cfg", []byte(src))
+	c.Assert(err, IsNil)
+	c.Check(string(res), Equals, expected)
+}
+
+// Source(): canonical forms of literals.
+func (ft *FormatTests) TestSource3(c *C) {
+	src := `a = +5
+b = 007
+c = 0XFF
+d = -0B1010
+e = 1_000
+f = 5.
+g = 00.5
+h = -1.5E+3
+i = +inf
+j = 1979-05-27T07:32:00Z
+k = +10MiB
+l = true
+m = "a\tb"
+n = 1m30s
+o = 1_000.5e3
+`
+	expected := `a = 5
+b = 7
+c = 0xFF
+d = -0b1010
+e = 1_000
+f = 5.0
+g = 0.5
+h = -1.5e+3
+i = inf
+j = 1979-05-27T07:32:00Z
+k = 10MiB
+l = true
+m = "a\tb"
+n = 1m30s
+o = 1_000.5e3
+`
+	res, err := format.Source("app.cfg", []byte(src))
+	c.Assert(err, IsNil)
+	c.Check(string(res), Equals, expected)
+}
+
+// Source(): array wrapping.
+func (ft *FormatTests) TestSource4(c *C) {
+	src := `[a]
+short = [ 1,2 ,3 ]
+long = ["aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc", "dddddddddd", "eeeeeeeeee", "ffff"]
+multi = [1,
+	2]
+`
+	expected := `[a]
+	short = [1, 2, 3]
+	long = [
+		"aaaaaaaaaa",
+		"bbbbbbbbbb",
+		"cccccccccc",
+		"dddddddddd",
+		"eeeeeeeeee",
+		"ffff"
+	]
+	multi = [
+		1,
+		2
+	]
+`
+	res, err := format.Source("app.cfg", []byte(src))
+	c.Assert(err, IsNil)
+	c.Check(string(res), Equals, expected)
+}
+
+// Source(): formatting is idempotent and preserves values.
+func (ft *FormatTests) TestSource5(c *C) {
+	srcs := []string{
+		"",
+		"# Comment only",
+		"a=1\n\n\n[b]\n\n\tc=[1,\n2]#x\n#y\n\n\n#z",
+		"[s] # c\n# d\nx=5.e3\ny=[\"a\",#1\n\"b\"#2\n]\nz=1979-05-27T07:32:00Z",
+	}
+
+	for _, src := range srcs {
+		res1, err := format.Source("app.cfg", []byte(src))
+		c.Assert(err, IsNil)
+		res2, err := format.Source("app.cfg", res1)
+		c.Assert(err, IsNil)
+		c.Check(string(res2), Equals, string(res1))
+
+		cfg1, cfg2 := config.NewConfiguration(), config.NewConfiguration()
+		c.Assert(cfg1.LoadString(src), IsNil)
+		c.Assert(cfg2.LoadString(string(res1)), IsNil)
+		c.Check(cfg2.String(), Equals, cfg1.String())
+	}
+}
+
+// Source(): syntax errors.
+func (ft *FormatTests) TestSource6(c *C) {
+	_, err := format.Source("app.cfg", []byte("[a]\nb = 'c'"))
+	c.Assert(err, NotNil)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Filename, Equals, "app.cfg")
+	c.Check(cerr.Line, Equals, 2)
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+}
+
+// Source(): doc comments preceded by blank lines.
+func (ft *FormatTests) TestSource7(c *C) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"a = 1\n\n# doc\n[s]\nb = 2", "a = 1\n\n# doc\n[s]\n\tb = 2\n"},
+		{"a = 1\n\n\n# doc\n[s]\nb = 2", "a = 1\n\n# doc\n[s]\n\tb = 2\n"},
+		{"a = 1\n\n# doc\nb = 2", "a = 1\n\n# doc\nb = 2\n"},
+		{"[s]\na = 1\n\n# doc\nb = 2", "[s]\n\ta = 1\n\n\t# doc\n\tb = 2\n"},
+	}
+
+	for _, test := range tests {
+		res, err := format.Source("app.cfg", []byte(test.src))
+		c.Assert(err, IsNil)
+		c.Check(string(res), Equals, test.expected, Commentf(test.src))
+		res, err = format.Source("app.cfg", []byte(test.expected))
+		c.Assert(err, IsNil)
+		c.Check(string(res), Equals, test.expected, Commentf(test.src))
+	}
+}
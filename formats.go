@@ -0,0 +1,339 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type (
+	// Format reads and writes configurations in a given syntax. Formats are
+	// registered with RegisterFormat and selected by name or by filename
+	// extension; see LoadFile, LoadFormat and Export.
+	Format interface {
+		// Name returns the name of the format (e.g. "json").
+		Name() string
+		// Extensions returns the filename extensions of the format, leading
+		// dot included (e.g. ".json").
+		Extensions() []string
+		// Decode loads the configuration read from r into c. Options
+		// override the ones already loaded. Name identifies the
+		// configuration in errors.
+		Decode(c *Configuration, name string, r io.Reader) error
+		// Encode writes c to w.
+		Encode(c *Configuration, w io.Writer) error
+	}
+
+	// builtinFormat is a format provided by the package. Decode is nil for
+	// formats that cannot be read.
+	builtinFormat struct {
+		name       string
+		extensions []string
+		decode     func(c *Configuration, name string, r io.Reader) error
+		encode     func(c *Configuration, w io.Writer) error
+	}
+)
+
+// NativeFormat is the name of the format of gp-config files. Files whose
+// extension is not registered are read in this format.
+const NativeFormat = "cfg"
+
+var (
+	// Formats registered by name and by extension, in lower case.
+	formats = struct {
+		sync.RWMutex
+		byName map[string]Format
+		byExt  map[string]Format
+	}{
+		byName: map[string]Format{},
+		byExt:  map[string]Format{},
+	}
+)
+
+func init() {
+	builtins := []*builtinFormat{
+		{NativeFormat, []string{".cfg"}, (*Configuration).LoadReader, (*Configuration).writeNative},
+		{"json", []string{".json"}, (*Configuration).LoadJSON, (*Configuration).ExportJSON},
+		{"ini", []string{".ini"}, (*Configuration).LoadINI, (*Configuration).writeINI},
+		{"yaml", []string{".yaml", ".yml"}, nil, (*Configuration).ExportYAML},
+		{"env", []string{".env"}, nil, func(c *Configuration, w io.Writer) error {
+			return c.ExportEnv(w, "")
+		}},
+	}
+	for _, f := range builtins {
+		RegisterFormat(f)
+	}
+}
+
+// RegisterFormat registers a format under its name and extensions, which
+// are case-insensitive. It replaces the format registered with the same
+// name, if any; extensions already registered are assigned to f.
+//
+// Formats provided by the package are "cfg" (.cfg, see NativeFormat),
+// "json" (.json, see LoadJSON and ExportJSON), "ini" (.ini, see LoadINI),
+// "yaml" (.yaml and .yml, see ExportYAML) and "env" (.env, see ExportEnv);
+// the last two cannot be read.
+func RegisterFormat(f Format) {
+	formats.Lock()
+	defer formats.Unlock()
+	name := strings.ToLower(f.Name())
+	if _, found := formats.byName[name]; found {
+		for ext, g := range formats.byExt {
+			if strings.ToLower(g.Name()) == name {
+				delete(formats.byExt, ext)
+			}
+		}
+	}
+	formats.byName[name] = f
+	for _, ext := range f.Extensions() {
+		formats.byExt[strings.ToLower(ext)] = f
+	}
+}
+
+// LookupFormat returns the format registered with given name, or for given
+// extension if name starts with a dot (".json"). It returns nil if there is
+// none.
+func LookupFormat(name string) Format {
+	formats.RLock()
+	defer formats.RUnlock()
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, ".") {
+		return formats.byExt[name]
+	}
+	return formats.byName[name]
+}
+
+// Formats returns the names of the registered formats, sorted.
+func Formats() []string {
+	formats.RLock()
+	defer formats.RUnlock()
+	names := make([]string, 0, len(formats.byName))
+	for name := range formats.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatOf returns the format of given file: the one registered with name
+// if not empty, or the one registered for its extension. Files whose
+// extension is not registered are in native format.
+func formatOf(filename, name string) (Format, error) {
+	if name != "" {
+		if f := LookupFormat(name); f != nil {
+			return f, nil
+		}
+		return nil, &ConfigurationError{
+			Filename: filename,
+			Code:     ErrUnsupportedFormat,
+			msg:      fmt.Sprintf("unknown format %q", name),
+		}
+	}
+	if ext := filepath.Ext(filename); ext != "" {
+		if f := LookupFormat(ext); f != nil {
+			return f, nil
+		}
+	}
+	return LookupFormat(NativeFormat), nil
+}
+
+// LoadFormat loads the configuration read from r in the format registered
+// with given name. Name identifies the configuration in errors.
+func (c *Configuration) LoadFormat(format, name string, r io.Reader) error {
+	f, err := formatOf(name, format)
+	if err != nil {
+		return err
+	}
+	return f.Decode(c, name, r)
+}
+
+// Export writes the configuration to w in the format registered with given
+// name.
+//
+//	err := cfg.Export(os.Stdout, "yaml")
+func (c *Configuration) Export(w io.Writer, format string) error {
+	f := LookupFormat(format)
+	if f == nil {
+		return &OptionError{
+			Code: ErrUnsupportedFormat,
+			msg:  fmt.Sprintf("unknown format %q", format),
+		}
+	}
+	return f.Encode(c, w)
+}
+
+// Name returns the name of the format.
+func (f *builtinFormat) Name() string {
+	return f.name
+}
+
+// Extensions returns the filename extensions of the format.
+func (f *builtinFormat) Extensions() []string {
+	return append([]string(nil), f.extensions...)
+}
+
+// Decode loads a configuration.
+func (f *builtinFormat) Decode(c *Configuration, name string, r io.Reader) error {
+	if f.decode == nil {
+		return &ConfigurationError{
+			Filename: name,
+			Code:     ErrUnsupportedFormat,
+			msg:      fmt.Sprintf("%s format cannot be read", f.name),
+		}
+	}
+	return f.decode(c, name, r)
+}
+
+// Encode writes a configuration.
+func (f *builtinFormat) Encode(c *Configuration, w io.Writer) error {
+	return f.encode(c, w)
+}
+
+// writeNative writes the configuration to w in native format: options
+// declared before the first section, then sections in declaration order.
+// Options of sections are indented with a tab.
+func (c *Configuration) writeNative(w io.Writer) error {
+	tree, err := c.tree()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, o := range tree[0].options {
+		fmt.Fprintf(&buf, "%s = %s\n", quoteKey(o.name), nativeValue(o.value))
+	}
+	for i, s := range tree[1:] {
+		if i > 0 || len(tree[0].options) > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "[%s]\n", quoteKey(s.name))
+		for _, o := range s.options {
+			fmt.Fprintf(&buf, "\t%s = %s\n", quoteKey(o.name), nativeValue(o.value))
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writeINI writes the configuration to w in INI format. Strings are
+// double-quoted and other values are written in literal form, as read by
+// LoadINI. Arrays are not supported.
+func (c *Configuration) writeINI(w io.Writer) error {
+	tree, err := c.tree()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, s := range tree {
+		if s.name != "" {
+			if i > 1 || len(tree[0].options) > 0 {
+				buf.WriteByte('\n')
+			}
+			if strings.ContainsAny(s.name, "]\n") {
+				return newOptionError(ErrUnsupportedFormat, JoinPath(s.name),
+					"section name cannot be written in INI format")
+			}
+			fmt.Fprintf(&buf, "[%s]\n", s.name)
+		}
+		for _, o := range s.options {
+			path := buildOptionPath(JoinPath(s.name), o.name)
+			if s.name == "" {
+				path = JoinPath(o.name)
+			}
+			if strings.ContainsAny(o.name, "=:\n") || strings.TrimSpace(o.name) != o.name {
+				return newOptionError(ErrUnsupportedFormat, path,
+					"option name cannot be written in INI format")
+			}
+			if reflect.ValueOf(o.value).Kind() == sliceType {
+				return newOptionError(ErrUnsupportedFormat, path,
+					"arrays cannot be written in INI format")
+			}
+			fmt.Fprintf(&buf, "%s = %s\n", o.name, nativeValue(o.value))
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// nativeValue returns the literal form of given value.
+func nativeValue(value interface{}) string {
+	elements := []string{}
+	isArray := exportValue(value, func(v interface{}) {
+		var s string
+		switch v := v.(type) {
+		case bool:
+			s = strconv.FormatBool(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = formatFloat(v)
+			if i := strings.IndexByte(s, 'e'); i >= 0 && strings.IndexByte(s, '.') < 0 {
+				// Exponents require a fraction: 1.0e+21.
+				s = s[:i] + ".0" + s[i:]
+			}
+		case time.Time:
+			s = v.UTC().Format(time.RFC3339)
+		case string:
+			s = nativeString(v)
+		case fmt.Stringer:
+			// time.Duration and Size.
+			s = v.String()
+		}
+		elements = append(elements, s)
+	})
+	if isArray {
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return elements[0]
+}
+
+// nativeString returns s as a double-quoted string. Valid escape sequences
+// are kept as is, since strings loaded from native files are not unescaped;
+// double quotes, other backslashes and control characters are escaped.
+func nativeString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, width := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\\' && i+1 < len(s) && strings.IndexByte("btnfr\"/\\", s[i+1]) >= 0:
+			buf.WriteString(s[i : i+2])
+			width = 2
+		case r == '\\' && i+6 <= len(s) && s[i+1] == 'u' && isHex(s[i+2:i+6]):
+			buf.WriteString(s[i : i+6])
+			width = 6
+		case r == '\\', r == '"':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&buf, `\u%04X`, r)
+		default:
+			buf.WriteRune(r)
+		}
+		i += width
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if strings.ContainsRune("0123456789abcdefABCDEF", r) == false {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package config_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"io"
+	"io/ioutil"
+	. "launchpad.net/gocheck"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	FormatsTests struct{}
+
+	// kvFormat reads and writes name=value lines, strings only.
+	kvFormat struct{}
+)
+
+var (
+	_ = Suite(&FormatsTests{})
+)
+
+func (kvFormat) Name() string         { return "KV" }
+func (kvFormat) Extensions() []string { return []string{".kv", ".KVS"} }
+
+func (kvFormat) Decode(c *config.Configuration, name string, r io.Reader) error {
+	var buf bytes.Buffer
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		parts := strings.SplitN(s.Text(), "=", 2)
+		fmt.Fprintf(&buf, "%s = %q\n", parts[0], parts[1])
+	}
+	return c.LoadReader(name, &buf)
+}
+
+func (kvFormat) Encode(c *config.Configuration, w io.Writer) error {
+	for _, o := range c.Options("") {
+		v, _ := c.GetString(o)
+		fmt.Fprintf(w, "%s=%s\n", o, v)
+	}
+	return nil
+}
+
+func writeConfig(c *C, name, contents string) string {
+	fn := filepath.Join(c.MkDir(), name)
+	c.Assert(ioutil.WriteFile(fn, []byte(contents), 0644), IsNil)
+	return fn
+}
+
+// LoadFile(): format is chosen by extension.
+func (ft *FormatsTests) TestLoadFile1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadFile(writeConfig(c, "a.cfg", "[server]\nhost = \"localhost\"\nport = 80")), IsNil)
+	c.Assert(cfg.LoadFile(writeConfig(c, "b.JSON", `{"server": {"port": 8080}}`)), IsNil)
+	c.Assert(cfg.LoadFile(writeConfig(c, "c.ini", "[server]\ndebug = true")), IsNil)
+	// Unregistered extension.
+	c.Assert(cfg.LoadFile(writeConfig(c, "d.conf", "[server]\nweight = 2.5")), IsNil)
+	c.Check(cfg.String(), Equals, `server.host = "localhost"
+server.port = 8080
+server.debug = true
+server.weight = 2.500000
+`)
+
+	// Errors of foreign formats are reported with the filename.
+	fn := writeConfig(c, "e.json", `{"a": null}`)
+	err := cfg.LoadFile(fn)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Filename, Equals, fn)
+	c.Check(cerr.Code, Equals, config.ErrInvalidValue)
+
+	err = cfg.LoadFile(writeConfig(c, "f.yaml", "a: 1"))
+	c.Check(errors.Is(err, config.ErrUnsupportedFormat), Equals, true)
+	c.Check(err, ErrorMatches, "yaml format cannot be read")
+}
+
+// Load(): format is chosen by name.
+func (ft *FormatsTests) TestLoad1(c *C) {
+	fn := writeConfig(c, "app.txt", "a = 1")
+	cfg := config.NewConfiguration()
+	_, err := cfg.Load(fn, &config.LoadOptions{Format: "ini"})
+	c.Assert(err, IsNil)
+	_, err = cfg.Load(fn, &config.LoadOptions{Format: "xml"})
+	c.Check(errors.Is(err, config.ErrUnsupportedFormat), Equals, true)
+	c.Check(err, ErrorMatches, `unknown format "xml"`)
+}
+
+// RegisterFormat(), LookupFormat(), Formats().
+func (ft *FormatsTests) TestRegisterFormat1(c *C) {
+	c.Check(config.LookupFormat("json").Name(), Equals, "json")
+	c.Check(config.LookupFormat(".YML").Name(), Equals, "yaml")
+	c.Check(config.LookupFormat(".txt"), IsNil)
+	c.Check(config.LookupFormat("kv"), IsNil)
+
+	config.RegisterFormat(kvFormat{})
+	c.Check(config.LookupFormat("kv"), Equals, config.Format(kvFormat{}))
+	c.Check(config.LookupFormat(".kvs"), Equals, config.Format(kvFormat{}))
+	c.Check(config.Formats(), DeepEquals, []string{"cfg", "env", "ini", "json", "kv", "yaml"})
+
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadFile(writeConfig(c, "app.kv", "host=localhost\nname=app")), IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.Export(&buf, "kv"), IsNil)
+	c.Check(buf.String(), Equals, "host=localhost\nname=app\n")
+}
+
+// Export(): native format.
+func (ft *FormatsTests) TestExport1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(exported), IsNil)
+	c.Assert(cfg.LoadJSON("big.json", strings.NewReader(`{"big": 1e21, "quote": "a\"b\\c"}`)), IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.Export(&buf, config.NativeFormat), IsNil)
+	c.Check(buf.String(), Equals, `version = [1, 0]
+ratio = 1
+big = 1.0e+21
+quote = "a\"b\\c"
+
+[server]
+	host = "<localhost>"
+	port = 80
+	weight = 2.5
+	created = 1979-05-27T07:32:00Z
+	timeout = 1m30s
+	max_body = 10MiB
+	limits = [inf, -inf, nan]
+	yes = true
+	max-conn = 100
+
+["example.com"]
+	hosts = ["a", "b"]
+`)
+
+	// Output is read back as is, except for strings read from other formats
+	// since escape sequences of native strings are not decoded.
+	dst := config.NewConfiguration()
+	c.Assert(dst.LoadString(buf.String()), IsNil)
+	changes := config.Diff(cfg, dst)
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Option, Equals, "quote")
+	c.Check(changes[0].New, Equals, `a\"b\\c`)
+}
+
+// Export(): INI format and errors.
+func (ft *FormatsTests) TestExport2(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString("name = \"app\"\n[server]\nport = 80\ntimeout = 1m30s\n[\"example.com\"]\nweight = 2.0"), IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.Export(&buf, "ini"), IsNil)
+	c.Check(buf.String(), Equals, `name = "app"
+
+[server]
+port = 80
+timeout = 1m30s
+
+[example.com]
+weight = 2.0
+`)
+	dst := config.NewConfiguration()
+	c.Assert(dst.LoadINI("app.ini", &buf), IsNil)
+	c.Check(config.Diff(cfg, dst), HasLen, 0)
+
+	c.Assert(cfg.LoadString("[server]\nhosts = [\"a\", \"b\"]"), IsNil)
+	buf.Reset()
+	err := cfg.Export(&buf, "ini")
+	c.Check(errors.Is(err, config.ErrUnsupportedFormat), Equals, true)
+	c.Check(err, ErrorMatches, "'server.hosts': arrays cannot be written in INI format")
+	c.Check(buf.Len(), Equals, 0)
+
+	err = cfg.Export(&buf, "xml")
+	c.Check(errors.Is(err, config.ErrUnsupportedFormat), Equals, true)
+	c.Check(err, ErrorMatches, `unknown format "xml"`)
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPSource is a source fetching a configuration document from an HTTP
+// server. Requests are conditional (If-None-Match) once the server has sent
+// an ETag, and no request is made while the document is fresh according to
+// the max-age directive of Cache-Control. Documents are validated before
+// being used, with the settings (key provider, profile, ...) of the
+// configuration they are loaded in; when the server cannot be reached,
+// answers with an error or sends an invalid document, the last valid
+// document is used, or the local copy stored in CacheFile. Err reports
+// invalid documents.
+type HTTPSource struct {
+	// Client sends requests; http.DefaultClient if nil.
+	Client *http.Client
+	// Format is the name of the format of the document (see
+	// RegisterFormat). By default, it is chosen by the extension of the
+	// URL path.
+	Format string
+	// CacheFile is the path of a local copy of the last valid document,
+	// loaded when the server is unavailable; empty disables the copy.
+	CacheFile string
+	// PollInterval is the interval at which Watch checks the document when
+	// it is not fresh; zero disables watching.
+	PollInterval time.Duration
+	// Validate, if not nil, checks a document before it is used.
+	Validate func(c *Configuration) error
+
+	url      string
+	mu       sync.Mutex
+	settings *Configuration // Empty configuration with the settings of last Load.
+	body     []byte         // Last valid document, nil if none.
+	etag     string         // ETag of body.
+	expires  time.Time      // Body is fresh until then.
+	err      error          // Error of last document fetched, if rejected.
+}
+
+// NewHTTPSource returns a source fetching the configuration document stored
+// at given URL.
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{url: url}
+}
+
+// Name returns the URL of the document.
+func (s *HTTPSource) Name() string {
+	return s.url
+}
+
+// Load loads the document, fetching it if needed.
+func (s *HTTPSource) Load(c *Configuration) error {
+	c.RLock()
+	settings := c.blankLocked()
+	c.RUnlock()
+	s.mu.Lock()
+	s.settings = settings
+	body, doc, _, err := s.document()
+	if err == nil && doc == nil {
+		doc, err = s.decode(body)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	c.merge(doc)
+	return nil
+}
+
+// Err returns the error that made the source reject the last document sent
+// by the server, or nil if it was used.
+func (s *HTTPSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Watch checks the document every PollInterval, once it is no longer
+// fresh. A change is reported when a new valid document is fetched.
+func (s *HTTPSource) Watch(stop <-chan struct{}) <-chan struct{} {
+	if s.PollInterval <= 0 {
+		return nil
+	}
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(s.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// Errors are reported by Load and Err.
+				s.mu.Lock()
+				_, _, changed, _ := s.document()
+				s.mu.Unlock()
+				if changed {
+					select {
+					case ch <- struct{}{}:
+					case <-stop:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// document returns the current document, and its configuration if it was
+// validated, and so decoded, by this call; changed is true if a new valid
+// document was fetched. Caller must hold the lock.
+func (s *HTTPSource) document() (body []byte, doc *Configuration, changed bool, err error) {
+	if s.body != nil && time.Now().Before(s.expires) {
+		return s.body, nil, false, nil
+	}
+	body, doc, err = s.fetch()
+	if err == nil && body == nil {
+		// Not modified.
+		s.err = nil
+		return s.body, nil, false, nil
+	}
+	if err != nil {
+		if errors.Is(err, ErrIO) == false {
+			// Invalid document; reported by Err.
+			s.err = err
+		}
+		if s.body != nil {
+			return s.body, nil, false, nil
+		}
+		if cached, doc, cerr := s.readCache(); cerr == nil {
+			s.body = cached
+			return s.body, doc, true, nil
+		}
+		return nil, nil, false, err
+	}
+	s.body, s.err = body, nil
+	return s.body, doc, true, nil
+}
+
+// decode returns the configuration of given valid document. Caller must hold
+// the lock.
+func (s *HTTPSource) decode(body []byte) (*Configuration, error) {
+	format, err := s.format()
+	if err != nil {
+		return nil, err
+	}
+	doc := s.blank()
+	if err := format.Decode(doc, s.url, bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// blank returns an empty configuration with the settings of last Load.
+// Caller must hold the lock.
+func (s *HTTPSource) blank() *Configuration {
+	if s.settings == nil {
+		return NewConfiguration()
+	}
+	return s.settings.clone()
+}
+
+// fetch requests the document and returns it with its configuration. It
+// returns a nil document if it was not modified. Caller must hold the lock.
+func (s *HTTPSource) fetch() ([]byte, *Configuration, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, nil, readError(s.url, err)
+	}
+	if s.body != nil && s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, readError(s.url, err)
+	}
+	defer resp.Body.Close()
+
+	maxAge, noStore := cacheControl(resp.Header.Get("Cache-Control"))
+	if resp.StatusCode == http.StatusNotModified && s.body != nil {
+		s.expires = time.Now().Add(maxAge)
+		return nil, nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, readError(s.url, fmt.Errorf("unexpected status %s", resp.Status))
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, readError(s.url, err)
+	}
+	// Invalid documents are not fetched again before they expire.
+	s.expires = time.Now().Add(maxAge)
+	doc, err := s.validate(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.etag = resp.Header.Get("ETag")
+	if noStore == false {
+		// The document is still usable if it cannot be copied.
+		s.writeCache(body)
+	}
+	return body, doc, nil
+}
+
+// validate returns the configuration of given document, or an error if it
+// cannot be loaded or is rejected by Validate. Caller must hold the lock.
+func (s *HTTPSource) validate(body []byte) (*Configuration, error) {
+	doc, err := s.decode(body)
+	if err != nil {
+		return nil, err
+	}
+	if s.Validate != nil {
+		if err := s.Validate(doc); err != nil {
+			return nil, &ConfigurationError{
+				Filename: s.url,
+				Code:     ErrInvalidValue,
+				msg:      err.Error(),
+				err:      err,
+			}
+		}
+	}
+	return doc, nil
+}
+
+func (s *HTTPSource) format() (Format, error) {
+	path := s.url
+	if u, err := url.Parse(s.url); err == nil {
+		path = u.Path
+	}
+	return formatOf(path, s.Format)
+}
+
+// readCache returns the local copy of the document and its configuration.
+// Caller must hold the lock.
+func (s *HTTPSource) readCache() ([]byte, *Configuration, error) {
+	if s.CacheFile == "" {
+		return nil, nil, os.ErrNotExist
+	}
+	body, err := ioutil.ReadFile(s.CacheFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := s.validate(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, doc, nil
+}
+
+// writeCache replaces the local copy of the document atomically.
+func (s *HTTPSource) writeCache(body []byte) error {
+	if s.CacheFile == "" {
+		return nil
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.CacheFile), filepath.Base(s.CacheFile)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.CacheFile)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// cacheControl returns the max-age directive of given Cache-Control header,
+// and whether responses may be stored. No-cache sets max-age to zero.
+func cacheControl(header string) (maxAge time.Duration, noStore bool) {
+	for _, directive := range strings.Split(header, ",") {
+		name, value := strings.TrimSpace(directive), ""
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, value = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+		}
+		switch strings.ToLower(name) {
+		case "max-age":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 && maxAge >= 0 {
+				maxAge = time.Duration(n) * time.Second
+			}
+		case "no-cache":
+			maxAge = -1
+		case "no-store":
+			noStore = true
+		}
+	}
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	return maxAge, noStore
+}
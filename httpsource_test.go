@@ -0,0 +1,246 @@
+package config_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type (
+	HTTPSourceTests struct{}
+
+	// configServer serves a configuration document.
+	configServer struct {
+		sync.Mutex
+		*httptest.Server
+		body         string
+		etag         string
+		cacheControl string
+		status       int
+		requests     []string // If-None-Match header of requests.
+	}
+)
+
+var (
+	_ = Suite(&HTTPSourceTests{})
+)
+
+func newConfigServer(body, etag string) *configServer {
+	s := &configServer{body: body, etag: etag, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+		s.requests = append(s.requests, r.Header.Get("If-None-Match"))
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		if s.etag != "" {
+			w.Header().Set("ETag", s.etag)
+			if r.Header.Get("If-None-Match") == s.etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		fmt.Fprint(w, s.body)
+	}))
+	return s
+}
+
+func (s *configServer) update(f func(s *configServer)) {
+	s.Lock()
+	defer s.Unlock()
+	f(s)
+}
+
+func (s *configServer) sent() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// HTTPSource: conditional requests.
+func (ht *HTTPSourceTests) TestHTTPSource1(c *C) {
+	server := newConfigServer("[server]\nport = 80", `"v1"`)
+	defer server.Close()
+	cfg := config.NewConfiguration()
+	src := config.NewHTTPSource(server.URL + "/app.cfg")
+	c.Check(src.Name(), Equals, server.URL+"/app.cfg")
+	c.Assert(cfg.AddSource(src), IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(80))
+
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(80))
+	server.update(func(s *configServer) {
+		s.body, s.etag = "[server]\nport = 8080", `"v2"`
+	})
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(8080))
+	c.Check(server.sent(), DeepEquals, []string{"", `"v1"`, `"v1"`})
+}
+
+// HTTPSource: Cache-Control.
+func (ht *HTTPSourceTests) TestHTTPSource2(c *C) {
+	server := newConfigServer(`{"a": 1}`, "")
+	defer server.Close()
+	server.cacheControl = "public, max-age=3600"
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.AddSource(config.NewHTTPSource(server.URL+"/app.json")), IsNil)
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+	// Document is fresh for an hour.
+	c.Check(server.sent(), HasLen, 1)
+
+	server.update(func(s *configServer) { s.cacheControl = "max-age=3600, no-cache" })
+	src := config.NewHTTPSource(server.URL + "/app.json")
+	c.Assert(cfg.AddSource(src), IsNil)
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(server.sent(), HasLen, 3)
+}
+
+// HTTPSource: fallback to last valid document and to local copy.
+func (ht *HTTPSourceTests) TestHTTPSource3(c *C) {
+	server := newConfigServer("a = 1", `"v1"`)
+	cache := filepath.Join(c.MkDir(), "app.cfg")
+	cfg := config.NewConfiguration()
+	src := config.NewHTTPSource(server.URL)
+	src.CacheFile = cache
+	c.Assert(cfg.AddSource(src), IsNil)
+
+	server.update(func(s *configServer) { s.status = http.StatusServiceUnavailable })
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+	server.Close()
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+
+	// Server is unavailable when application starts.
+	cfg = config.NewConfiguration()
+	src = config.NewHTTPSource(server.URL)
+	src.CacheFile = cache
+	c.Assert(cfg.AddSource(src), IsNil)
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+
+	// No local copy.
+	cfg = config.NewConfiguration()
+	err := cfg.AddSource(config.NewHTTPSource(server.URL))
+	c.Check(errors.Is(err, config.ErrIO), Equals, true)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Filename, Equals, server.URL)
+}
+
+// HTTPSource: invalid documents are not used.
+func (ht *HTTPSourceTests) TestHTTPSource4(c *C) {
+	server := newConfigServer("[server]\nport = 80", `"v1"`)
+	defer server.Close()
+	cfg := config.NewConfiguration()
+	src := config.NewHTTPSource(server.URL)
+	src.Validate = func(c *config.Configuration) error {
+		if port, _ := c.GetInt("server.port"); port <= 0 {
+			return fmt.Errorf("'server.port': invalid port %d", port)
+		}
+		return nil
+	}
+	c.Assert(cfg.AddSource(src), IsNil)
+
+	server.update(func(s *configServer) { s.body, s.etag = "[server]\nport = ", `"v2"` })
+	c.Check(cfg.Reload(), IsNil)
+	c.Check(errors.Is(src.Err(), config.ErrSyntax), Equals, true)
+	server.update(func(s *configServer) { s.body, s.etag = "[server]\nport = -1", `"v3"` })
+	c.Check(cfg.Reload(), IsNil)
+	err := src.Err()
+	c.Check(errors.Is(err, config.ErrInvalidValue), Equals, true)
+	c.Check(err, ErrorMatches, "'server.port': invalid port -1")
+	// Last valid document is still used.
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(80))
+	c.Check(server.sent(), DeepEquals, []string{"", `"v1"`, `"v1"`})
+
+	server.update(func(s *configServer) { s.body, s.etag = "[server]\nport = 8080", `"v4"` })
+	c.Check(cfg.Reload(), IsNil)
+	c.Check(src.Err(), IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(8080))
+
+	// First document is invalid.
+	server.update(func(s *configServer) { s.body = "[server]\nport = " })
+	err = config.NewConfiguration().AddSource(config.NewHTTPSource(server.URL))
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+}
+
+// HTTPSource.Watch().
+func (ht *HTTPSourceTests) TestHTTPSource5(c *C) {
+	server := newConfigServer("a = 1", `"v1"`)
+	defer server.Close()
+	src := config.NewHTTPSource(server.URL)
+	c.Check(src.Watch(nil), IsNil)
+	src.PollInterval = 10 * time.Millisecond
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.AddSource(src), IsNil)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	reloaded := make(chan error, 1)
+	cfg.Watch(stop, func(err error) { reloaded <- err })
+	server.update(func(s *configServer) { s.body, s.etag = "a = 2", `"v2"` })
+	select {
+	case err := <-reloaded:
+		c.Check(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("configuration not reloaded")
+	}
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(2))
+}
+
+// HTTPSource: valid, then invalid document; invalid documents are not
+// fetched again before they expire.
+func (ht *HTTPSourceTests) TestHTTPSource6(c *C) {
+	server := newConfigServer("a = 1", "")
+	defer server.Close()
+	cfg := config.NewConfiguration()
+	src := config.NewHTTPSource(server.URL)
+	c.Assert(cfg.AddSource(src), IsNil)
+
+	server.update(func(s *configServer) { s.body, s.cacheControl = "a = ", "max-age=3600" })
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+	c.Check(errors.Is(src.Err(), config.ErrSyntax), Equals, true)
+	c.Assert(cfg.Reload(), IsNil)
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+	c.Check(server.sent(), HasLen, 2)
+}
+
+// HTTPSource: documents are validated with the settings of the
+// configuration, and decoded once.
+func (ht *HTTPSourceTests) TestHTTPSource7(c *C) {
+	encrypted, err := config.Encrypt("s3cr3t", testKey)
+	c.Assert(err, IsNil)
+	resolved := 0
+	config.RegisterSecretResolver("httpsourcetest", config.SecretResolverFunc(func(ref string) (string, error) {
+		resolved++
+		return "t0k3n", nil
+	}))
+	server := newConfigServer("[db]\npassword = \""+encrypted+"\"\ntoken = secret(\"httpsourcetest:db\")\n[db@prod]\nhost = \"db.example.com\"", `"v1"`)
+	defer server.Close()
+
+	cfg := config.NewConfiguration()
+	cfg.SetKeyProvider(config.StaticKey(testKey))
+	cfg.SetProfile("prod")
+	c.Assert(cfg.AddSource(config.NewHTTPSource(server.URL+"/app.cfg")), IsNil)
+	c.Check(cfg.GetStringDefault("db.password", ""), Equals, "s3cr3t")
+	c.Check(cfg.GetStringDefault("db.token", ""), Equals, "t0k3n")
+	c.Check(cfg.GetStringDefault("db.host", ""), Equals, "db.example.com")
+	c.Check(resolved, Equals, 1)
+
+	// Not decrypted without key.
+	err = config.NewConfiguration().AddSource(config.NewHTTPSource(server.URL + "/app.cfg"))
+	c.Check(errors.Is(err, config.ErrSecret), Equals, true)
+}
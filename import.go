@@ -0,0 +1,439 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cbonello/gp-config/scanner"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// importer loads a configuration written in a foreign format.
+	importer struct {
+		name string         // Name of configuration in errors.
+		src  string         // Input.
+		c    *Configuration // Options imported so far.
+	}
+
+	jsonImporter struct {
+		*importer
+		dec *json.Decoder
+	}
+)
+
+// LoadJSON loads the configuration read from r in JSON format. Input must be
+// an object; members holding objects are sections and other members are
+// options declared before the first section. Objects may not be nested
+// deeper. Types are inferred from values as follows, so that the output of
+// ExportJSON is loaded back as is:
+//
+//	true, false                     bool
+//	number without fraction         int64
+//	nor exponent (80)
+//	other numbers (1.0, 1e+21)      float64
+//	RFC 3339 string                 time.Time
+//	("1979-05-27T07:32:00Z")
+//	duration string ("1m30s")       time.Duration
+//	size string ("10MiB")           Size
+//	secret string                   secret string, resolved
+//	("secret(\"env:DB_TOKEN\")")
+//	other strings                   string
+//	arrays                          arrays
+//
+// Integers and floating-point numbers may be mixed in arrays; elements are
+// converted to the type of the first one. Null values, empty arrays and
+// arrays of objects or arrays are rejected. Strings "inf", "-inf" and "nan"
+// are loaded as strings.
+//
+// Options override the ones already loaded, as with LoadFile. Nothing is
+// loaded if an error is returned. Name identifies the configuration in
+// errors.
+func (c *Configuration) LoadJSON(name string, r io.Reader) error {
+	im, err := newImporter(c, name, r)
+	if err != nil {
+		return err
+	}
+	ji := &jsonImporter{importer: im, dec: json.NewDecoder(strings.NewReader(im.src))}
+	ji.dec.UseNumber()
+	if err := ji.load(); err != nil {
+		return err
+	}
+	c.merge(im.c)
+	return nil
+}
+
+// LoadINI loads the configuration read from r in INI format:
+//
+//	; Comment.
+//	name = value
+//	[section]
+//	name: value
+//
+// Lines starting with ';' or '#' are comments; option names are separated
+// from values by '=' or ':'. Options declared before the first section are
+// global options. Section and option names are used as is, so that a section
+// declared as [example.com] is accessed as `"example.com"`. Values are read
+// as native literals (true, 80, 1.0, 1979-05-27T07:32:00Z, 1m30s, 10MiB, ...)
+// when possible and as strings otherwise; surrounding double or single
+// quotes are removed from strings. Secrets (secret("env:DB_TOKEN")) are
+// resolved. Arrays are not supported.
+//
+// Options override the ones already loaded, as with LoadFile. Nothing is
+// loaded if an error is returned. Name identifies the configuration in
+// errors.
+func (c *Configuration) LoadINI(name string, r io.Reader) error {
+	im, err := newImporter(c, name, r)
+	if err != nil {
+		return err
+	}
+	if err := im.loadINI(); err != nil {
+		return err
+	}
+	c.merge(im.c)
+	return nil
+}
+
+func newImporter(c *Configuration, name string, r io.Reader) (*importer, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, readError(name, err)
+	}
+	c.RLock()
+	tmp := c.blankLocked()
+	c.RUnlock()
+	return &importer{name: name, src: string(src), c: tmp}, nil
+}
+
+// newError returns an error located at given offset of input.
+func (im *importer) newError(offset int, code ErrorCode, format string, args ...interface{}) *ConfigurationError {
+	if offset > len(im.src) {
+		offset = len(im.src)
+	}
+	start := strings.LastIndexByte(im.src[:offset], '\n') + 1
+	end := strings.IndexByte(im.src[start:], '\n')
+	if end < 0 {
+		end = len(im.src) - start
+	}
+	line := strings.Count(im.src[:start], "\n") + 1
+	column := offset - start + 1
+	return &ConfigurationError{
+		Filename:  im.name,
+		Line:      line,
+		Column:    column,
+		EndLine:   line,
+		EndColumn: column + 1,
+		Code:      code,
+		msg:       fmt.Sprintf(format, args...),
+		source:    strings.TrimSuffix(im.src[start:start+end], "\r"),
+	}
+}
+
+// set sets an option; secrets are resolved and encrypted values decrypted. Offset locates the value in
+// errors.
+func (im *importer) set(path string, value interface{}, offset int) error {
+	if s, isString := value.(string); isString && isEncrypted(s) {
+		plain, derr := im.c.decrypt(path, s)
+		if derr != nil {
+			err := im.newError(offset, ErrSecret, "'%s': %s", path, derr)
+			err.err = derr
+			return err
+		}
+		im.c.setSecret(path, plain, s)
+		return nil
+	}
+	ref, isSecret := value.(SecretRef)
+	if isSecret == false {
+		im.c.setOption(path, value)
+		return nil
+	}
+	secret, rerr := im.c.secret(path, string(ref))
+	if rerr != nil {
+		err := im.newError(offset, ErrSecret, "'%s': %s", path, rerr)
+		err.err = rerr
+		return err
+	}
+	im.c.setSecret(path, secret, ref)
+	return nil
+}
+
+// literalValue returns the value of s if it is a single literal of one of
+// given kinds.
+func literalValue(s string, kinds ...scanner.Kind) (interface{}, bool) {
+	l := scanner.New("", s)
+	l.NextToken()
+	if l.Token.Raw != s {
+		return nil, false
+	}
+	for _, k := range kinds {
+		if l.Token.Kind == k {
+			if k == TkSize {
+				return Size(l.Token.Value.(int64)), true
+			}
+			if k == scanner.TkSecret {
+				return SecretRef(l.Token.Value.(string)), true
+			}
+			return l.Token.Value, true
+		}
+	}
+	return nil, false
+}
+
+// kindOf returns the kind of literal of given value.
+func kindOf(v interface{}) scanner.Kind {
+	switch v.(type) {
+	case bool:
+		return TkBool
+	case int64:
+		return TkInt
+	case float64:
+		return TkFloat
+	case time.Time:
+		return TkDate
+	case time.Duration:
+		return TkDuration
+	case Size:
+		return TkSize
+	}
+	return TkString
+}
+
+// array converts the elements of an array to the type of the first one, as
+// the parser does. Offsets locate the elements in input.
+func (im *importer) array(path string, values []interface{}, offsets []int) ([]interface{}, error) {
+	first := kindOf(values[0])
+	for i, v := range values {
+		kind := kindOf(v)
+		switch {
+		case kind == first:
+			continue
+		case first == TkFloat && kind == TkInt:
+			values[i] = float64(v.(int64))
+			continue
+		case first == TkInt && kind == TkFloat:
+			// Floating point number can be safely converted to an integer?
+			f := v.(float64)
+			if math.Floor(f) == f && f >= math.MinInt64 && f < math.MaxInt64 {
+				values[i] = int64(f)
+				continue
+			}
+		}
+		err := im.newError(offsets[i], ErrTypeMismatch, "'%s': cannot use type %s as type %s",
+			path, kind, first)
+		err.Hint = "array elements must share the same type"
+		return nil, err
+	}
+	return values, nil
+}
+
+func (ji *jsonImporter) load() error {
+	t, offset, err := ji.token()
+	if err == io.EOF || (err == nil && t != json.Delim('{')) {
+		return ji.newError(offset, ErrSyntax, "expected JSON object")
+	} else if err != nil {
+		return err
+	}
+	if err := ji.members(""); err != nil {
+		return err
+	}
+	if _, offset, err := ji.token(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return ji.newError(offset, ErrSyntax, "unexpected data after JSON object")
+	}
+	return nil
+}
+
+// token returns the next token and its offset in input. The error is io.EOF
+// at the end of input and a *ConfigurationError otherwise.
+func (ji *jsonImporter) token() (json.Token, int, error) {
+	offset := int(ji.dec.InputOffset())
+	for offset < len(ji.src) && strings.IndexByte(" \t\r\n:,", ji.src[offset]) >= 0 {
+		offset++
+	}
+	t, err := ji.dec.Token()
+	if err == nil || err == io.EOF {
+		return t, offset, err
+	}
+	if err == io.ErrUnexpectedEOF {
+		return nil, len(ji.src), ji.newError(len(ji.src), ErrSyntax, "unexpected end of input")
+	}
+	// Offsets of syntax errors vary; errors are reported at the start of
+	// the offending token.
+	return nil, offset, ji.newError(offset, ErrSyntax, "%s", err)
+}
+
+// next is similar to token but reports the end of input as an error.
+func (ji *jsonImporter) next() (json.Token, int, error) {
+	t, offset, err := ji.token()
+	if err == io.EOF {
+		return nil, offset, ji.newError(offset, ErrSyntax, "unexpected end of input")
+	}
+	return t, offset, err
+}
+
+// members loads the members of an object up to the closing brace.
+func (ji *jsonImporter) members(section string) error {
+	for ji.dec.More() {
+		t, _, err := ji.next()
+		if err != nil {
+			return err
+		}
+		name := t.(string)
+		path := JoinPath(name)
+		if section != "" {
+			path = JoinPath(section, name)
+		}
+		t, offset, err := ji.next()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'):
+			if section != "" {
+				return ji.newError(offset, ErrInvalidValue, "'%s': sections cannot be nested", path)
+			}
+			if err := ji.members(name); err != nil {
+				return err
+			}
+		case json.Delim('['):
+			v, err := ji.array(path)
+			if err != nil {
+				return err
+			}
+			ji.c.setOption(path, v)
+		default:
+			v, err := ji.scalar(path, t, offset)
+			if err != nil {
+				return err
+			}
+			if err := ji.set(path, v, offset); err != nil {
+				return err
+			}
+		}
+	}
+	// Closing brace.
+	_, _, err := ji.next()
+	return err
+}
+
+func (ji *jsonImporter) array(path string) ([]interface{}, error) {
+	values, offsets := []interface{}{}, []int{}
+	for ji.dec.More() {
+		t, offset, err := ji.next()
+		if err != nil {
+			return nil, err
+		}
+		if t == json.Delim('{') || t == json.Delim('[') {
+			return nil, ji.newError(offset, ErrInvalidValue,
+				"'%s': array elements must be booleans, numbers or strings", path)
+		}
+		v, err := ji.scalar(path, t, offset)
+		if err != nil {
+			return nil, err
+		}
+		if _, isSecret := v.(SecretRef); isSecret {
+			return nil, ji.newError(offset, ErrInvalidValue, "'%s': secrets cannot be array elements", path)
+		}
+		if s, isString := v.(string); isString && isEncrypted(s) {
+			return nil, ji.newError(offset, ErrInvalidValue, "'%s': encrypted values cannot be array elements", path)
+		}
+		values, offsets = append(values, v), append(offsets, offset)
+	}
+	// Closing bracket.
+	_, offset, err := ji.next()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ji.newError(offset, ErrInvalidValue, "'%s': empty arrays are not supported", path)
+	}
+	return ji.importer.array(path, values, offsets)
+}
+
+func (ji *jsonImporter) scalar(path string, t json.Token, offset int) (interface{}, error) {
+	switch t := t.(type) {
+	case bool:
+		return t, nil
+	case json.Number:
+		if strings.ContainsAny(string(t), ".eE") == false {
+			if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+				return i, nil
+			}
+			return nil, ji.newError(offset, ErrInvalidValue, "'%s': integer %s out of range", path, t)
+		}
+		f, err := strconv.ParseFloat(string(t), 64)
+		if err != nil {
+			return nil, ji.newError(offset, ErrInvalidValue, "'%s': number %s out of range", path, t)
+		}
+		return f, nil
+	case string:
+		if v, ok := literalValue(t, TkDate, TkDuration, TkSize, scanner.TkSecret); ok {
+			return v, nil
+		}
+		return t, nil
+	}
+	return nil, ji.newError(offset, ErrInvalidValue, "'%s': null values are not supported", path)
+}
+
+func (im *importer) loadINI() error {
+	section := ""
+	for offset := 0; offset < len(im.src); {
+		end := strings.IndexByte(im.src[offset:], '\n')
+		if end < 0 {
+			end = len(im.src) - offset
+		}
+		line := strings.TrimSuffix(im.src[offset:offset+end], "\r")
+		start := offset + len(line) - len(strings.TrimLeft(line, " \t"))
+		line = strings.TrimSpace(line)
+		offset += end + 1
+
+		switch {
+		case line == "", line[0] == ';', line[0] == '#':
+			continue
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				return im.newError(start+len(line), ErrSyntax, "expected ']'")
+			}
+			if section = strings.TrimSpace(line[1 : len(line)-1]); section == "" {
+				return im.newError(start, ErrSyntax, "missing section name")
+			}
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return im.newError(start, ErrSyntax, "expected '=' or ':' after option name")
+		}
+		name := strings.TrimSpace(line[:i])
+		if name == "" {
+			return im.newError(start, ErrSyntax, "missing option name")
+		}
+		path := JoinPath(name)
+		if section != "" {
+			path = JoinPath(section, name)
+		}
+		value := line[i+1:]
+		offset := start + i + 1 + len(value) - len(strings.TrimLeft(value, " \t"))
+		if err := im.set(path, iniValue(strings.TrimSpace(value)), offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iniValue returns the value of given INI literal.
+func iniValue(s string) interface{} {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	if v, ok := literalValue(s, TkBool, TkInt, TkFloat, TkDate, TkDuration, TkSize, scanner.TkSecret); ok {
+		return v
+	}
+	return s
+}
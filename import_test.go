@@ -0,0 +1,193 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"strings"
+	"time"
+)
+
+type (
+	ImportTests struct{}
+)
+
+var (
+	_ = Suite(&ImportTests{})
+)
+
+// LoadJSON().
+func (it *ImportTests) TestLoadJSON1(c *C) {
+	cfg := config.NewConfiguration()
+	err := cfg.LoadJSON("app.json", strings.NewReader(`{
+  "version": [1, 0],
+  "debug": false,
+  "server": {
+    "host": "localhost",
+    "port": 80,
+    "weight": 2.5,
+    "created": "1979-05-27T07:32:00Z",
+    "timeout": "1m30s",
+    "max_body": "10MiB",
+    "ratios": [1.5, 2]
+  },
+  "example.com": {
+    "hosts": ["a", "b"]
+  }
+}`))
+	c.Assert(err, IsNil)
+	c.Check(cfg.String(), Equals, `version = [1, 0]
+debug = false
+server.host = "localhost"
+server.port = 80
+server.weight = 2.500000
+server.created = 1979-05-27T07:32:00Z
+server.timeout = 1m30s
+server.max_body = 10MiB
+server.ratios = [1.500000, 2.000000]
+"example.com".hosts = ["a", "b"]
+`)
+	c.Check(cfg.Sections(), DeepEquals, []string{"", `"example.com"`, "server"})
+	created, err := cfg.GetDate("server.created")
+	c.Check(err, IsNil)
+	c.Check(created, Equals, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC))
+	timeout, err := cfg.GetDuration("server.timeout")
+	c.Check(err, IsNil)
+	c.Check(timeout, Equals, 90*time.Second)
+}
+
+// LoadJSON(): output of ExportJSON is loaded back as is.
+func (it *ImportTests) TestLoadJSON2(c *C) {
+	src := config.NewConfiguration()
+	c.Assert(src.LoadString(exported), IsNil)
+	var buf bytes.Buffer
+	c.Assert(src.ExportJSON(&buf), IsNil)
+
+	dst := config.NewConfiguration()
+	c.Assert(dst.LoadJSON("exported.json", &buf), IsNil)
+	changes := config.Diff(src, dst)
+	c.Check(changes, HasLen, 1)
+	// Inf and nan are exported as strings.
+	c.Check(changes[0].Option, Equals, "server.limits")
+}
+
+// LoadJSON(): options override the ones already loaded and may be decoded.
+func (it *ImportTests) TestLoadJSON3(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString("[server]\nhost = \"localhost\"\nport = 80"), IsNil)
+	c.Assert(cfg.LoadJSON("override.json", strings.NewReader(`{"SERVER": {"port": 8080}}`)), IsNil)
+	var server struct {
+		Host string
+		Port int64
+	}
+	c.Assert(cfg.Decode("server", &server), IsNil)
+	c.Check(server.Host, Equals, "localhost")
+	c.Check(server.Port, Equals, int64(8080))
+	c.Check(cfg.Sections(), DeepEquals, []string{"server"})
+}
+
+// LoadJSON(): errors.
+func (it *ImportTests) TestLoadJSON4(c *C) {
+	tests := []struct {
+		src          string
+		code         config.ErrorCode
+		line, column int
+		msg          string
+	}{
+		{`[1, 2]`, config.ErrSyntax, 1, 1, "expected JSON object"},
+		{``, config.ErrSyntax, 1, 1, "expected JSON object"},
+		{"{\n  \"a\": 1,\n  \"b\": }", config.ErrSyntax, 3, 8, ""},
+		{`{"a": 1`, config.ErrSyntax, 1, 8, ""},
+		{`{"a": 1} 2`, config.ErrSyntax, 1, 10, "unexpected data after JSON object"},
+		{`{"a": null}`, config.ErrInvalidValue, 1, 7, "'a': null values are not supported"},
+		{`{"a": []}`, config.ErrInvalidValue, 1, 8, "'a': empty arrays are not supported"},
+		{`{"a": [[1]]}`, config.ErrInvalidValue, 1, 8, "'a': array elements must be booleans, numbers or strings"},
+		{`{"a": {"b": {"c": 1}}}`, config.ErrInvalidValue, 1, 13, "'a.b': sections cannot be nested"},
+		{`{"a": [1, 2.5]}`, config.ErrTypeMismatch, 1, 11, "'a': cannot use type float64 as type int64"},
+		{`{"a": [1, "b"]}`, config.ErrTypeMismatch, 1, 11, "'a': cannot use type string as type int64"},
+		{`{"a": 99999999999999999999}`, config.ErrInvalidValue, 1, 7, "'a': integer 99999999999999999999 out of range"},
+	}
+	for _, test := range tests {
+		cfg := config.NewConfiguration()
+		c.Assert(cfg.LoadString("a = 0"), IsNil)
+		err := cfg.LoadJSON("app.json", strings.NewReader(test.src))
+		var cerr *config.ConfigurationError
+		c.Assert(errors.As(err, &cerr), Equals, true, Commentf("%q", test.src))
+		c.Check(cerr.Code, Equals, test.code, Commentf("%q", test.src))
+		c.Check(cerr.Filename, Equals, "app.json")
+		c.Check(cerr.Line, Equals, test.line, Commentf("%q", test.src))
+		c.Check(cerr.Column, Equals, test.column, Commentf("%q", test.src))
+		if test.msg != "" {
+			// Messages of encoding/json vary with Go versions.
+			c.Check(cerr.Error(), Equals, test.msg)
+		}
+		// Nothing is loaded on error.
+		c.Check(cfg.String(), Equals, "a = 0\n")
+	}
+}
+
+// LoadINI().
+func (it *ImportTests) TestLoadINI1(c *C) {
+	cfg := config.NewConfiguration()
+	err := cfg.LoadINI("app.ini", strings.NewReader(`; Legacy settings.
+name = legacy app
+debug = true
+
+[server]
+# Listening address.
+host: "localhost"
+port = 80
+weight = 2.5
+timeout = 1m30s
+max_body = 10MiB
+path = '/var/www'
+empty =
+
+[example.com]
+url = http://example.com/?a=b
+`))
+	c.Assert(err, IsNil)
+	c.Check(cfg.String(), Equals, `name = "legacy app"
+debug = true
+server.host = "localhost"
+server.port = 80
+server.weight = 2.500000
+server.timeout = 1m30s
+server.max_body = 10MiB
+server.path = "/var/www"
+server.empty = ""
+"example.com".url = "http://example.com/?a=b"
+`)
+	port, err := cfg.GetInt("server.port")
+	c.Check(err, IsNil)
+	c.Check(port, Equals, int64(80))
+	url, err := cfg.GetString(`"example.com".url`)
+	c.Check(err, IsNil)
+	c.Check(url, Equals, "http://example.com/?a=b")
+}
+
+// LoadINI(): errors.
+func (it *ImportTests) TestLoadINI2(c *C) {
+	tests := []struct {
+		src          string
+		line, column int
+		msg          string
+	}{
+		{"a = 1\n  [server", 2, 10, "expected ']'"},
+		{"[ ]", 1, 1, "missing section name"},
+		{"[server]\n\tport 80", 2, 2, "expected '=' or ':' after option name"},
+		{"= 80", 1, 1, "missing option name"},
+	}
+	for _, test := range tests {
+		cfg := config.NewConfiguration()
+		err := cfg.LoadINI("app.ini", strings.NewReader(test.src))
+		var cerr *config.ConfigurationError
+		c.Assert(errors.As(err, &cerr), Equals, true, Commentf("%q", test.src))
+		c.Check(cerr.Code, Equals, config.ErrSyntax)
+		c.Check(cerr.Line, Equals, test.line, Commentf("%q", test.src))
+		c.Check(cerr.Column, Equals, test.column, Commentf("%q", test.src))
+		c.Check(cerr.Error(), Equals, test.msg)
+		c.Check(cfg.Len(), Equals, 0)
+	}
+}
@@ -0,0 +1,47 @@
+package textdiff_test
+
+import (
+	"github.com/cbonello/gp-config/internal/textdiff"
+	. "launchpad.net/gocheck"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	TestingT(t)
+}
+
+type (
+	DiffTests struct{}
+)
+
+var (
+	_ = Suite(&DiffTests{})
+)
+
+// Unified().
+func (dt *DiffTests) TestUnified1(c *C) {
+	c.Check(textdiff.Unified("a", "b", []byte("x\n"), []byte("x\n")), IsNil)
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13"
+	c.Check(string(textdiff.Unified("a", "b", []byte(a), []byte(b))), Equals, `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+\ No newline at end of file
+`)
+
+	c.Check(string(textdiff.Unified("a", "b", nil, []byte("x\n"))), Equals,
+		"--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n")
+}
@@ -0,0 +1,280 @@
+// Package lint reports suspicious constructs of gp-config configuration
+// files; constructs the parser accepts but that are likely mistakes, such as
+// options declared twice.
+//
+//	issues, err := lint.Source("app.cfg", src, lint.Rules())
+//	for _, issue := range issues {
+//	    fmt.Println(issue)
+//	}
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"github.com/cbonello/gp-config/ast"
+	"github.com/cbonello/gp-config/scanner"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	// Rule is a check performed by the linter.
+	Rule struct {
+		Name        string
+		Description string
+		check       func(l *linter, f *ast.File)
+	}
+
+	// Issue is a problem reported by a rule.
+	Issue struct {
+		Filename string `json:"file"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+		Rule     string `json:"rule"`
+		Message  string `json:"message"`
+	}
+
+	linter struct {
+		filename string
+		rule     *Rule
+		issues   []Issue
+	}
+)
+
+var (
+	rules = []*Rule{
+		{
+			Name:        "duplicate-option",
+			Description: "option declared twice; last declaration wins",
+			check:       checkDuplicateOptions,
+		},
+		{
+			Name:        "duplicate-section",
+			Description: "section declared twice; options are merged",
+			check:       checkDuplicateSections,
+		},
+		{
+			Name:        "mixed-array",
+			Description: "array mixing integers and floating-point numbers; elements are converted",
+			check:       checkMixedArrays,
+		},
+		{
+			Name:        "plaintext-secret",
+			Description: "password, token or key stored in plain text",
+			check:       checkSecrets,
+		},
+		{
+			Name:        "case-conflict",
+			Description: "section or option names differing only by case",
+			check:       checkCaseConflicts,
+		},
+	}
+
+	// Names of options holding secrets.
+	secretNameRe = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api_?key|private_?key|credentials?)`)
+	// Values that look like secrets whatever the option name: AWS access
+	// keys and PEM private keys.
+	secretValueRe = regexp.MustCompile(`^(AKIA[0-9A-Z]{16}|-----BEGIN [A-Z ]*PRIVATE KEY-----)`)
+)
+
+// Rules returns all rules, in the order they are documented.
+func Rules() []*Rule {
+	return append([]*Rule(nil), rules...)
+}
+
+// Lookup returns the rule with given name, or nil.
+func Lookup(name string) *Rule {
+	for _, r := range rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Check applies given rules to a tree and returns the issues found, sorted
+// by position.
+func Check(f *ast.File, rules []*Rule) []Issue {
+	l := &linter{filename: f.Filename}
+	for _, r := range rules {
+		l.rule = r
+		r.check(l, f)
+	}
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i], l.issues[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.issues
+}
+
+// Source parses given configuration and applies rules to it. Syntax errors
+// are returned as *config.ConfigurationError values.
+func Source(filename string, src []byte, rules []*Rule) ([]Issue, error) {
+	p, err := config.NewReaderParser(filename, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	f, cerr := p.ParseAST()
+	if cerr != nil {
+		return nil, cerr
+	}
+	return Check(f, rules), nil
+}
+
+// String formats an issue as file:line:col: message (rule).
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", i.Filename, i.Line, i.Column,
+		i.Message, i.Rule)
+}
+
+func (l *linter) report(pos scanner.Position, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{
+		Filename: l.filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Rule:     l.rule.Name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// scopes calls f for each group of options sharing a section; sections
+// declared several times form a single group. Options declared before first
+// section have an empty section name.
+func scopes(f *ast.File, fn func(section string, options []*ast.Option)) {
+	fn("", f.Options)
+	names := []string{} // Names as first declared.
+	groups := map[string][]*ast.Option{}
+	for _, s := range f.Sections {
+		name := strings.ToLower(s.Name.Name)
+		if _, found := groups[name]; found == false {
+			names = append(names, s.Name.Name)
+		}
+		groups[name] = append(groups[name], s.Options...)
+	}
+	for _, name := range names {
+		fn(name, groups[strings.ToLower(name)])
+	}
+}
+
+// duplicates calls fn for each key whose name, compared case-insensitively,
+// was used by a previous key of the list.
+func duplicates(keys []*ast.Key, fn func(key, first *ast.Key)) {
+	seen := map[string]*ast.Key{}
+	for _, k := range keys {
+		name := strings.ToLower(k.Name)
+		if first, found := seen[name]; found {
+			fn(k, first)
+		} else {
+			seen[name] = k
+		}
+	}
+}
+
+func optionKeys(options []*ast.Option) []*ast.Key {
+	keys := make([]*ast.Key, len(options))
+	for i, o := range options {
+		keys[i] = o.Key
+	}
+	return keys
+}
+
+func sectionKeys(f *ast.File) []*ast.Key {
+	keys := make([]*ast.Key, len(f.Sections))
+	for i, s := range f.Sections {
+		keys[i] = s.Name
+	}
+	return keys
+}
+
+func checkDuplicateOptions(l *linter, f *ast.File) {
+	scopes(f, func(section string, options []*ast.Option) {
+		duplicates(optionKeys(options), func(key, first *ast.Key) {
+			if key.Name == first.Name {
+				l.report(key.Pos(), "'%s': option redefined (previous declaration at %s)",
+					config.JoinPath(nonEmpty(section, key.Name)...), first.Pos())
+			}
+		})
+	})
+}
+
+func checkDuplicateSections(l *linter, f *ast.File) {
+	duplicates(sectionKeys(f), func(key, first *ast.Key) {
+		if key.Name == first.Name {
+			l.report(key.Pos(), "'%s': section redeclared (previous declaration at %s)",
+				config.JoinPath(key.Name), first.Pos())
+		}
+	})
+}
+
+func checkCaseConflicts(l *linter, f *ast.File) {
+	duplicates(sectionKeys(f), func(key, first *ast.Key) {
+		if key.Name != first.Name {
+			l.report(key.Pos(), "'%s': section name differs only by case from '%s' (%s)",
+				config.JoinPath(key.Name), config.JoinPath(first.Name), first.Pos())
+		}
+	})
+	scopes(f, func(section string, options []*ast.Option) {
+		duplicates(optionKeys(options), func(key, first *ast.Key) {
+			if key.Name != first.Name {
+				l.report(key.Pos(), "'%s': option name differs only by case from '%s' (%s)",
+					config.JoinPath(nonEmpty(section, key.Name)...),
+					config.JoinPath(nonEmpty(section, first.Name)...), first.Pos())
+			}
+		})
+	})
+}
+
+func checkMixedArrays(l *linter, f *ast.File) {
+	scopes(f, func(section string, options []*ast.Option) {
+		for _, o := range options {
+			a, ok := o.Value.(*ast.Array)
+			if ok == false {
+				continue
+			}
+			first := a.Elements[0].Kind
+			for _, e := range a.Elements[1:] {
+				if (first == scanner.TkInt && e.Kind == scanner.TkFloat) ||
+					(first == scanner.TkFloat && e.Kind == scanner.TkInt) {
+					l.report(e.Pos(), "'%s': %s %s converted to %s (type of first element)",
+						config.JoinPath(nonEmpty(section, o.Key.Name)...), e.Kind, e.Raw, first)
+					break
+				}
+			}
+		}
+	})
+}
+
+func checkSecrets(l *linter, f *ast.File) {
+	scopes(f, func(section string, options []*ast.Option) {
+		for _, o := range options {
+			v, ok := o.Value.(*ast.Literal)
+			if ok == false || v.Kind != scanner.TkString || v.Value.(string) == "" {
+				continue
+			}
+			if strings.HasPrefix(v.Value.(string), config.EncryptedPrefix) {
+				// Encrypted by "gpconfig encrypt".
+				continue
+			}
+			if secretNameRe.MatchString(o.Key.Name) || secretValueRe.MatchString(v.Value.(string)) {
+				l.report(v.Pos(), "'%s': secret stored in plain text",
+					config.JoinPath(nonEmpty(section, o.Key.Name)...))
+			}
+		}
+	})
+}
+
+// nonEmpty returns given names, without empty ones.
+func nonEmpty(names ...string) []string {
+	result := names[:0]
+	for _, n := range names {
+		if n != "" {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type (
+	// LoadOptions controls how Load locates configuration files.
+	LoadOptions struct {
+		// Optional makes a missing file not an error; nothing is loaded then.
+		Optional bool
+		// SearchPaths lists the directories where a relative filename is
+		// looked up, in order; the first existing file is loaded.
+		// Environment variables ($HOME, ${XDG_CONFIG_HOME}, ...) are
+		// expanded and directories referencing an unset variable are
+		// skipped. Filename is used as is if the list is empty.
+		SearchPaths []string
+		// Format is the name of the format of the file (see RegisterFormat).
+		// By default, it is chosen by filename extension.
+		Format string
+	}
+
+	// LoadResult describes what Load loaded.
+	LoadResult struct {
+		Path     string   // File found, empty if none.
+		Searched []string // Files looked up, in order.
+	}
+)
+
+// DefaultSearchPaths returns the usual locations of the configuration files
+// of given application: the current directory, the user's configuration
+// directory (e.g. $XDG_CONFIG_HOME/app) and, on Unix systems, /etc/app.
+func DefaultSearchPaths(app string) []string {
+	paths := []string{"."}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, app))
+	}
+	if runtime.GOOS != "windows" {
+		paths = append(paths, filepath.Join("/etc", app))
+	}
+	return paths
+}
+
+// Loaded returns true if a file was found and loaded.
+func (r LoadResult) Loaded() bool {
+	return r.Path != ""
+}
+
+// Load loads the configuration stored in given file according to opts; nil
+// opts loads a required file without search paths, like LoadFile. Result
+// reports the file that was loaded, if any.
+//
+//	res, err := cfg.Load("app.cfg", &config.LoadOptions{
+//	    SearchPaths: config.DefaultSearchPaths("app"),
+//	})
+func (c *Configuration) Load(filename string, opts *LoadOptions) (LoadResult, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	result := LoadResult{}
+	candidates := []string{filename}
+	if len(opts.SearchPaths) > 0 && filepath.IsAbs(filename) == false {
+		candidates = candidates[:0]
+		for _, dir := range opts.SearchPaths {
+			if dir, ok := expandPath(dir); ok {
+				candidates = append(candidates, filepath.Join(dir, filename))
+			}
+		}
+	}
+
+	var notFound error = os.ErrNotExist
+	for _, fn := range candidates {
+		result.Searched = append(result.Searched, fn)
+		format, err := formatOf(fn, opts.Format)
+		if err != nil {
+			return result, err
+		}
+		contents, err := ioutil.ReadFile(fn)
+		if err != nil {
+			if os.IsNotExist(err) {
+				notFound = err
+				continue
+			}
+			return result, readError(fn, err)
+		}
+		result.Path = fn
+		if err := format.Decode(c, fn, bytes.NewReader(contents)); err != nil {
+			return result, err
+		}
+		return result, nil
+	}
+
+	if opts.Optional {
+		return result, nil
+	}
+	msg := notFound.Error()
+	if len(candidates) != 1 {
+		msg = fmt.Sprintf("file not found in search paths (%s)",
+			strings.Join(result.Searched, ", "))
+		notFound = os.ErrNotExist
+	}
+	return result, &ConfigurationError{
+		Filename: filename,
+		Code:     ErrNotFound,
+		msg:      msg,
+		err:      notFound,
+	}
+}
+
+// LoadReader loads the configuration read from r. Name identifies the
+// configuration in errors (see ConfigurationError.Filename).
+func (c *Configuration) LoadReader(name string, r io.Reader) error {
+	p, err := NewReaderParser(name, r)
+	if err != nil {
+		return readError(name, err)
+	}
+	if err := p.Parse(c); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LoadStream is similar to LoadReader but parses the configuration as it is
+// read, so that memory use does not depend on the size of the input. Error
+// messages are less detailed; see NewStreamParser.
+func (c *Configuration) LoadStream(name string, r io.Reader) error {
+	if err := NewStreamParser(name, r).Parse(c); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LoadFS loads the configuration stored in given file of fsys; an embed.FS
+// holding default settings for instance. The format of the file is chosen by
+// extension, as with LoadFile. Path is used in errors.
+func (c *Configuration) LoadFS(fsys fs.FS, path string) error {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return readError(path, err)
+	}
+	defer f.Close()
+	format, err := formatOf(path, "")
+	if err != nil {
+		return err
+	}
+	return format.Decode(c, path, f)
+}
+
+// readError reports an error returned while reading given configuration.
+func readError(name string, err error) *ConfigurationError {
+	code := ErrIO
+	if errors.Is(err, fs.ErrNotExist) {
+		code = ErrNotFound
+	}
+	return &ConfigurationError{
+		Filename: name,
+		Code:     code,
+		msg:      err.Error(),
+		err:      err,
+	}
+}
+
+// expandPath expands the environment variables of given path. It returns
+// false if path references an unset or empty variable.
+func expandPath(path string) (string, bool) {
+	ok := true
+	path = os.Expand(path, func(name string) string {
+		v := os.Getenv(name)
+		if v == "" {
+			ok = false
+		}
+		return v
+	})
+	return path, ok
+}
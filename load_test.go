@@ -0,0 +1,242 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/cbonello/gp-config"
+	"io"
+	"io/ioutil"
+	. "launchpad.net/gocheck"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"testing/iotest"
+)
+
+type (
+	LoadTests struct {
+		dirs []string
+	}
+)
+
+var (
+	_ = Suite(&LoadTests{})
+)
+
+// createDir creates a temporary directory holding given files.
+func (lt *LoadTests) createDir(c *C, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "LoadTest")
+	c.Assert(err, IsNil)
+	lt.dirs = append(lt.dirs, dir)
+	for name, contents := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		c.Assert(err, IsNil)
+	}
+	return dir
+}
+
+func (lt *LoadTests) TearDownTest(c *C) {
+	for _, dir := range lt.dirs {
+		os.RemoveAll(dir)
+	}
+	lt.dirs = nil
+}
+
+// LoadFile(): missing files are reported.
+func (lt *LoadTests) TestLoad1(c *C) {
+	dir := lt.createDir(c, nil)
+	fn := filepath.Join(dir, "rumpelstilzchen.cfg")
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadFile(fn)
+	c.Assert(err, NotNil)
+	c.Check(errors.Is(err, config.ErrNotFound), Equals, true)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Check(err, ErrorMatches, "open .*rumpelstilzchen.cfg: no such file or directory")
+}
+
+// Load(): optional files.
+func (lt *LoadTests) TestLoad2(c *C) {
+	dir := lt.createDir(c, map[string]string{"app.cfg": "a = 1"})
+
+	cfg := config.NewConfiguration()
+	opts := &config.LoadOptions{Optional: true}
+	res, err := cfg.Load(filepath.Join(dir, "rumpelstilzchen.cfg"), opts)
+	c.Check(err, IsNil)
+	c.Check(res.Loaded(), Equals, false)
+	c.Check(res.Path, Equals, "")
+	c.Check(cfg.Len(), Equals, 0)
+
+	fn := filepath.Join(dir, "app.cfg")
+	res, err = cfg.Load(fn, opts)
+	c.Check(err, IsNil)
+	c.Check(res.Loaded(), Equals, true)
+	c.Check(res.Path, Equals, fn)
+	c.Check(res.Searched, EqualSlice, []string{fn})
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+}
+
+// Load(): search paths.
+func (lt *LoadTests) TestLoad3(c *C) {
+	dir1 := lt.createDir(c, map[string]string{"other.cfg": "a = 1"})
+	dir2 := lt.createDir(c, map[string]string{"app.cfg": "a = 2"})
+	dir3 := lt.createDir(c, map[string]string{"app.cfg": "a = 3"})
+
+	cfg := config.NewConfiguration()
+	opts := &config.LoadOptions{SearchPaths: []string{dir1, dir2, dir3}}
+	res, err := cfg.Load("app.cfg", opts)
+	c.Check(err, IsNil)
+	c.Check(res.Path, Equals, filepath.Join(dir2, "app.cfg"))
+	c.Check(res.Searched, EqualSlice, []string{
+		filepath.Join(dir1, "app.cfg"),
+		filepath.Join(dir2, "app.cfg"),
+	})
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(2))
+
+	// Search paths are ignored for absolute filenames.
+	res, err = cfg.Load(filepath.Join(dir1, "other.cfg"), opts)
+	c.Check(err, IsNil)
+	c.Check(res.Path, Equals, filepath.Join(dir1, "other.cfg"))
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(1))
+}
+
+// Load(): environment variables in search paths.
+func (lt *LoadTests) TestLoad4(c *C) {
+	dir := lt.createDir(c, map[string]string{"app.cfg": "a = 1"})
+	os.Setenv("GPCONFIG_LOAD_TEST", dir)
+	defer os.Unsetenv("GPCONFIG_LOAD_TEST")
+	os.Unsetenv("GPCONFIG_UNSET")
+
+	cfg := config.NewConfiguration()
+	opts := &config.LoadOptions{
+		SearchPaths: []string{"${GPCONFIG_UNSET}/app", "$GPCONFIG_LOAD_TEST"},
+	}
+	res, err := cfg.Load("app.cfg", opts)
+	c.Check(err, IsNil)
+	c.Check(res.Path, Equals, filepath.Join(dir, "app.cfg"))
+	c.Check(res.Searched, EqualSlice, []string{filepath.Join(dir, "app.cfg")})
+}
+
+// Load(): required file not found in search paths.
+func (lt *LoadTests) TestLoad5(c *C) {
+	dir1 := lt.createDir(c, nil)
+	dir2 := lt.createDir(c, nil)
+
+	cfg := config.NewConfiguration()
+	opts := &config.LoadOptions{SearchPaths: []string{dir1, dir2}}
+	res, err := cfg.Load("app.cfg", opts)
+	c.Assert(err, NotNil)
+	c.Check(res.Loaded(), Equals, false)
+	c.Check(res.Searched, HasLen, 2)
+	c.Check(errors.Is(err, config.ErrNotFound), Equals, true)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Check(err, ErrorMatches, "file not found in search paths \\(.*app.cfg, .*app.cfg\\)")
+
+	// Syntax errors are reported along with the file found.
+	ioutil.WriteFile(filepath.Join(dir2, "app.cfg"), []byte("[app"), 0644)
+	res, err = cfg.Load("app.cfg", opts)
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+	c.Check(res.Path, Equals, filepath.Join(dir2, "app.cfg"))
+}
+
+// DefaultSearchPaths().
+func (lt *LoadTests) TestDefaultSearchPaths1(c *C) {
+	paths := config.DefaultSearchPaths("app")
+	c.Assert(len(paths) >= 2, Equals, true)
+	c.Check(paths[0], Equals, ".")
+	c.Check(filepath.Base(paths[1]), Equals, "app")
+}
+
+// LoadReader(): name is reported in errors.
+func (lt *LoadTests) TestLoadReader1(c *C) {
+	cfg := config.NewConfiguration()
+	err := cfg.LoadReader("defaults", strings.NewReader("[server]\n\tport = 8080"))
+	c.Check(err, IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(8080))
+
+	err = cfg.LoadReader("defaults", strings.NewReader("[server"))
+	c.Assert(err, NotNil)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Filename, Equals, "defaults")
+	c.Check(cerr.Code, Equals, config.ErrSyntax)
+}
+
+// LoadReader(): read errors.
+func (lt *LoadTests) TestLoadReader2(c *C) {
+	ioErr := errors.New("connection reset")
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadReader("remote", iotest.ErrReader(ioErr))
+	c.Assert(err, NotNil)
+	c.Check(errors.Is(err, config.ErrIO), Equals, true)
+	c.Check(errors.Is(err, ioErr), Equals, true)
+}
+
+// LoadFS().
+func (lt *LoadTests) TestLoadFS1(c *C) {
+	fsys := fstest.MapFS{
+		"defaults/app.cfg": &fstest.MapFile{Data: []byte("[server]\n\tport = 80")},
+		"broken.cfg":       &fstest.MapFile{Data: []byte("port = ")},
+	}
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadFS(fsys, "defaults/app.cfg")
+	c.Check(err, IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(80))
+
+	err = cfg.LoadFS(fsys, "broken.cfg")
+	c.Assert(err, NotNil)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Filename, Equals, "broken.cfg")
+	c.Check(cerr.Line, Equals, 1)
+
+	err = cfg.LoadFS(fsys, "missing.cfg")
+	c.Check(errors.Is(err, config.ErrNotFound), Equals, true)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+}
+
+// LoadStream().
+func (lt *LoadTests) TestLoadStream1(c *C) {
+	contents := "[server]\n\tport = 8080\n\thosts = [\"a\", \"b\"]"
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadStream("stream", iotest.OneByteReader(strings.NewReader(contents)))
+	c.Check(err, IsNil)
+	c.Check(cfg.GetIntDefault("server.port", 0), Equals, int64(8080))
+	c.Check(cfg.GetStringArrayDefault("server.hosts", nil), EqualSlice, []string{"a", "b"})
+}
+
+// LoadStream(): source lines are only quoted for errors on the line of the
+// last token read.
+func (lt *LoadTests) TestLoadStream2(c *C) {
+	cfg := config.NewConfiguration()
+	err := cfg.LoadStream("stream", strings.NewReader("a = 1\nb = [1, true]\nc = 2"))
+	c.Assert(err, NotNil)
+	var cerr *config.ConfigurationError
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Diagnostic(false), Equals, "stream:2:9: cannot use type bool as type int64\n"+
+		"    b = [1, true]\n"+
+		"            ^~~~\n"+
+		"    hint: array elements must share the same type\n")
+
+	err = cfg.LoadStream("stream", strings.NewReader("[empty]\n\n[full]\na = 1"))
+	c.Assert(errors.As(err, &cerr), Equals, true)
+	c.Check(cerr.Code, Equals, config.ErrEmptySection)
+	c.Check(cerr.Line, Equals, 1)
+	c.Check(cerr.Diagnostic(false), Equals, "stream:1:7: empty section empty\n"+
+		"    hint: sections must declare at least one option\n")
+}
+
+// LoadStream(): read errors.
+func (lt *LoadTests) TestLoadStream3(c *C) {
+	ioErr := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("a = 1\nb = "), iotest.ErrReader(ioErr))
+
+	cfg := config.NewConfiguration()
+	err := cfg.LoadStream("stream", r)
+	c.Assert(err, NotNil)
+	c.Check(errors.Is(err, config.ErrIO), Equals, true)
+	c.Check(errors.Is(err, ioErr), Equals, true)
+}
@@ -2,43 +2,76 @@ package config
 
 import (
 	"fmt"
+	"github.com/cbonello/gp-config/ast"
+	"github.com/cbonello/gp-config/scanner"
+	"io"
 	"io/ioutil"
 	"math"
-	"os"
 	"reflect"
+	"sort"
 	"time"
 )
 
 type (
 	// Parser context.
 	Parser struct {
-		lexer *lexer
-		// Number of options created or updated in current section (to detect
-		// empty sections).
-		optionCount uint
+		lexer    *scanner.Scanner
+		comments []*ast.Comment // Comments not attached to a node yet.
+		// Options of profile sections, applied once the configuration is
+		// parsed so that they override the base sections.
+		profiles *Configuration
+		// Section being parsed and whether it is a profile section;
+		// ParseAll resumes it after errors.
+		section   string
+		inProfile bool
 	}
+
+	// ErrorList records the errors detected by ParseAll sorted by position.
+	ErrorList []*ConfigurationError
 )
 
-// NewParser instanciates a parser for given configuration file.
+// NewParser instanciates a parser for given configuration file. An error is
+// returned if the file cannot be read, including when it does not exist.
 func NewParser(filename string) (*Parser, error) {
 	contents, err := ioutil.ReadFile(filename)
 	if err != nil {
-		// Ok if file does not exists but report read errors.
-		if os.IsNotExist(err) == false {
-			return nil, err
-		}
-		return nil, nil
+		return nil, err
+	}
+	p := &Parser{
+		lexer: scanner.New(filename, string(contents)),
+	}
+	return p, nil
+}
+
+// NewReaderParser instanciates a parser for the configuration read from r.
+// Name identifies the configuration in error messages.
+func NewReaderParser(name string, r io.Reader) (*Parser, error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
 	}
 	p := &Parser{
-		lexer: NewLexer(filename, string(contents)),
+		lexer: scanner.New(name, string(contents)),
 	}
 	return p, nil
 }
 
+// NewStreamParser instanciates a parser reading the configuration from r as
+// parsing progresses, with memory use bounded by the length of the longest
+// token rather than by the size of the input. Errors only quote the source
+// line when it is the line of the last token read. Name identifies the
+// configuration in error messages.
+func NewStreamParser(name string, r io.Reader) *Parser {
+	p := &Parser{
+		lexer: scanner.NewReader(name, r),
+	}
+	return p
+}
+
 // NewStringParser instanciates a parser for given configuration string.
 func NewStringParser(contents string) *Parser {
 	p := &Parser{
-		lexer: NewLexer(":string:", contents),
+		lexer: scanner.New(":string:", contents),
 	}
 	return p
 }
@@ -46,21 +79,101 @@ func NewStringParser(contents string) *Parser {
 // Parse parses a configuration stored either in a file or a string.
 func (p *Parser) Parse(c *Configuration) (err *ConfigurationError) {
 	if p != nil {
-		if p.lexer.NextToken(); p.lexer.Token.Kind == TkError {
-			return &ConfigurationError{
-				Filename: p.lexer.Filename,
-				Line:     p.lexer.Token.Line,
-				Column:   p.lexer.Token.Column,
-				msg:      p.lexer.Token.Value.(string),
+		err = p.parse(c)
+		// Input may look truncated after a read error.
+		if rerr := p.inputError(); rerr != nil {
+			return rerr
+		}
+		if err == nil && p.profiles != nil {
+			c.merge(p.profiles)
+		}
+	}
+	return err
+}
+
+func (p *Parser) parse(c *Configuration) (err *ConfigurationError) {
+	if p.next(); p.lexer.Token.Kind == TkError {
+		return p.newError(&p.lexer.Token, "%s", p.lexer.Token.Value)
+	}
+	// Files may only contain comments and empty lines.
+	for p.skipEmptyLines(); p.lexer.Token.Kind != TkEOF; p.skipEmptyLines() {
+		if err := p.parseConfig(c, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputError returns the error reported by the reader of a stream parser, if
+// any.
+func (p *Parser) inputError() *ConfigurationError {
+	if err := p.lexer.Err(); err != nil {
+		return readError(p.lexer.Filename, err)
+	}
+	return nil
+}
+
+// ParseAll is similar to Parse but does not stop at the first error; parser
+// resynchronizes at the next line, in the section being parsed, and all
+// errors are returned sorted by position. Options are applied to c only if
+// no error was detected, unless partial is true in which case all valid
+// options are applied.
+func (p *Parser) ParseAll(c *Configuration, partial bool) (errs ErrorList) {
+	if p != nil {
+		c.RLock()
+		staging := c.blankLocked()
+		c.RUnlock()
+		p.next()
+		for p.skipEmptyLines(); p.lexer.Token.Kind != TkEOF; p.skipEmptyLines() {
+			if err := p.parseConfig(staging, p.section); err != nil {
+				errs = append(errs, err)
+				p.resync(err)
 			}
 		}
-		for p.lexer.Token.Kind != TkEOF {
-			if err := p.parseConfig(c, ""); err != nil {
-				return err
+		if rerr := p.inputError(); rerr != nil {
+			return ErrorList{rerr}
+		}
+		sort.Sort(errs)
+		if len(errs) == 0 || partial {
+			c.merge(staging)
+			if p.profiles != nil {
+				c.merge(p.profiles)
 			}
 		}
 	}
-	return nil
+	return errs
+}
+
+// resync skips tokens up to the beginning of next line to recover from given
+// error.
+func (p *Parser) resync(err *ConfigurationError) {
+	// Parser is already at the beginning of a line if error was detected on
+	// a previous one (e.g. empty sections).
+	if p.lexer.Token.Line > err.Line {
+		return
+	}
+	if p.lexer.Token.Kind != TkEOL && p.lexer.Token.Kind != TkEOF {
+		p.lexer.SkipLine()
+		p.next()
+	}
+	if p.lexer.Token.Kind == TkEOL {
+		p.next()
+	}
+}
+
+// next reads next token. Comments are only returned by scanner while
+// building syntax trees; they are recorded until attached to a node. Sizes
+// are returned by scanner as int64 values.
+func (p *Parser) next() {
+	for p.lexer.NextToken(); p.lexer.Token.Kind == scanner.TkComment; p.lexer.NextToken() {
+		p.comments = append(p.comments, &ast.Comment{
+			Hash: p.lexer.Token.Pos(),
+			Text: p.lexer.Token.Raw,
+		})
+	}
+	if p.lexer.Token.Kind == TkSize && p.lexer.Mode&scanner.ScanComments == 0 {
+		p.lexer.Token.Value = Size(p.lexer.Token.Value.(int64))
+	}
 }
 
 func (p *Parser) skipEmptyLines() {
@@ -68,7 +181,7 @@ func (p *Parser) skipEmptyLines() {
 		if p.lexer.Token.Kind != TkEOL {
 			return
 		}
-		p.lexer.NextToken()
+		p.next()
 	}
 }
 
@@ -77,40 +190,60 @@ func (p *Parser) parseConfig(c *Configuration, section string) (err *Configurati
 	if p.lexer.Token.Kind == TkLBracket {
 		return p.parseSection(c, section)
 	}
-	if p.lexer.Token.Kind == TkIdentifier {
+	if p.isKey() {
+		if section != "" && p.inProfile {
+			// Options following an error in a profile section.
+			c = p.profileOptions(c)
+		}
 		return p.parseOptions(c, section)
 	}
 	return p.expectedError()
 }
 
 func (p *Parser) parseSection(c *Configuration, section string) (err *ConfigurationError) {
-	if p.lexer.NextToken(); p.lexer.Token.Kind == TkIdentifier {
+	if p.next(); p.isKey() {
 		// Remember section name for error reporting.
 		currentSection := p.lexer.Token
-		section = p.formatOptionName(section, p.lexer.Token.Value.(string))
-		optionCountSave := p.optionCount
-		if p.lexer.NextToken(); p.lexer.Token.Kind == TkRBracket {
+		// Sections cannot be nested.
+		section = p.formatOptionName("", p.lexer.Token.Value.(string))
+		_, _, isProfile := profileSection(p.lexer.Token.Value.(string))
+		if isProfile {
+			c = p.profileOptions(c)
+		}
+		p.section, p.inProfile = section, isProfile
+		if p.next(); p.lexer.Token.Kind == TkRBracket {
 			// Set error to end of section declaration.
 			currentSection.Column = p.lexer.Token.Column
-			if p.lexer.NextToken(); p.lexer.Token.Kind == TkEOL {
-				p.lexer.NextToken()
-				err := p.parseConfig(c, section)
+			currentSection.EndColumn = p.lexer.Token.EndColumn
+			if p.next(); p.lexer.Token.Kind == TkEOL {
+				p.skipEmptyLines()
 				// No options were declared in section?
-				if p.optionCount == optionCountSave {
+				if p.isKey() == false && p.lexer.Token.Kind != TkError {
 					return p.emptySectionError(p.lexer.Filename,
 						&currentSection)
 				}
-				return err
+				return p.parseOptions(c, section)
 			}
 		}
 	}
 	return p.unexpectedError()
 }
 
+// profileOptions returns the configuration recording the options of profile
+// sections for c.
+func (p *Parser) profileOptions(c *Configuration) *Configuration {
+	if p.profiles == nil {
+		c.RLock()
+		p.profiles = c.blankLocked()
+		c.RUnlock()
+	}
+	return p.profiles
+}
+
 func (p *Parser) parseOptions(c *Configuration, section string) (err *ConfigurationError) {
-	for p.lexer.Token.Kind == TkIdentifier {
+	for p.isKey() {
 		option := p.lexer.Token.Value.(string)
-		p.lexer.NextToken()
+		p.next()
 		if err = p.parseOption(c, section, option); err != nil {
 			return err
 		}
@@ -118,18 +251,39 @@ func (p *Parser) parseOptions(c *Configuration, section string) (err *Configurat
 	return nil
 }
 
+// isKey returns true if current token is a section or option name; either an
+// identifier or a quoted key. Constants inf and nan are only floats in value
+// position; they are turned into identifiers here.
+func (p *Parser) isKey() bool {
+	if p.lexer.Token.Kind == TkFloat && (p.lexer.Token.Raw == "inf" || p.lexer.Token.Raw == "nan") {
+		p.lexer.Token.Kind, p.lexer.Token.Value = TkIdentifier, p.lexer.Token.Raw
+	}
+	return p.lexer.Token.Kind == TkIdentifier ||
+		(p.lexer.Token.Kind == TkString && p.lexer.Token.Value != "")
+}
+
 func (p *Parser) parseOption(c *Configuration, section, option string) (err *ConfigurationError) {
 	if p.lexer.Token.Kind == TkEqual {
-		if p.lexer.NextToken(); p.lexer.Token.Kind == TkLBracket {
-			p.lexer.NextToken()
+		if p.next(); p.lexer.Token.Kind == TkLBracket {
+			p.next()
 			err = p.parseArray(c, section, option)
 		} else {
-			err = p.parseValue(c)
 			option = p.formatOptionName(section, option)
-			c.setOption(option, p.lexer.Token.Value)
-			// One option was created or updated.
-			p.optionCount++
-			p.lexer.NextToken()
+			if err = p.parseValue(c, option); err != nil {
+				return err
+			}
+			switch p.lexer.Token.Kind {
+			case scanner.TkSecret:
+				err = p.setSecret(c, option)
+			case TkString:
+				err = p.setString(c, option)
+			default:
+				c.setOption(option, p.lexer.Token.Value)
+			}
+			if err != nil {
+				return err
+			}
+			p.next()
 		}
 		if err != nil {
 			return err
@@ -145,19 +299,57 @@ func (p *Parser) parseOption(c *Configuration, section, option string) (err *Con
 	return p.unexpectedError()
 }
 
-func (p *Parser) parseValue(c *Configuration) (err *ConfigurationError) {
+func (p *Parser) parseValue(c *Configuration, option string) (err *ConfigurationError) {
 	switch p.lexer.Token.Kind {
-	case TkBool, TkInt, TkFloat, TkDate, TkString:
+	case TkBool, TkInt, TkFloat, TkDate, TkString, TkDuration, TkSize, scanner.TkSecret:
 		return nil
+	case TkError:
+		// Malformed values are reported with the name of the option.
+		return p.newError(&p.lexer.Token, "'%s': %s", option, p.lexer.Token.Value)
+	case TkEOL, TkEOF:
+		err = p.unexpectedError()
+		err.Hint = "value of option '" + option + "' is missing"
+		return err
 	default:
 		return p.unexpectedError()
 	}
 }
 
+// setSecret sets option to the secret referenced by current token.
+func (p *Parser) setSecret(c *Configuration, option string) *ConfigurationError {
+	ref := p.lexer.Token.Value.(string)
+	value, rerr := c.secret(option, ref)
+	if rerr != nil {
+		err := p.newError(&p.lexer.Token, "'%s': %s", option, rerr)
+		err.Code, err.err = ErrSecret, rerr
+		return err
+	}
+	c.setSecret(option, value, SecretRef(ref))
+	return nil
+}
+
+// setString sets option to the string of current token, decrypting it if
+// it is an encrypted value.
+func (p *Parser) setString(c *Configuration, option string) *ConfigurationError {
+	s := p.lexer.Token.Value.(string)
+	if isEncrypted(s) == false {
+		c.setOption(option, s)
+		return nil
+	}
+	value, derr := c.decrypt(option, s)
+	if derr != nil {
+		err := p.newError(&p.lexer.Token, "'%s': %s", option, derr)
+		err.Code, err.err = ErrSecret, derr
+		return err
+	}
+	c.setSecret(option, value, s)
+	return nil
+}
+
 func (p *Parser) parseArray(c *Configuration, section, option string) (err *ConfigurationError) {
 	skipEOL := func(p *Parser) {
 		for p.lexer.Token.Kind == TkEOL {
-			p.lexer.NextToken()
+			p.next()
 		}
 	}
 
@@ -166,7 +358,19 @@ func (p *Parser) parseArray(c *Configuration, section, option string) (err *Conf
 	array := []interface{}{}
 	for {
 		currentValue := p.lexer.Token
-		if err = p.parseValue(c); err != nil {
+		if err = p.parseValue(c, p.formatOptionName(section, option)); err != nil {
+			return err
+		}
+		if currentValue.Kind == scanner.TkSecret {
+			err = p.newError(&currentValue, "'%s': secrets cannot be array elements",
+				p.formatOptionName(section, option))
+			err.Code = ErrInvalidValue
+			return err
+		}
+		if currentValue.Kind == TkString && isEncrypted(currentValue.Value.(string)) {
+			err = p.newError(&currentValue, "'%s': encrypted values cannot be array elements",
+				p.formatOptionName(section, option))
+			err.Code = ErrInvalidValue
 			return err
 		}
 		if firstValue.Kind != currentValue.Kind {
@@ -176,30 +380,40 @@ func (p *Parser) parseArray(c *Configuration, section, option string) (err *Conf
 			}
 		}
 		array = append(array, currentValue.Value)
-		p.lexer.NextToken()
+		p.next()
 		skipEOL(p)
-		//p.lexer.NextToken()
+		//p.next()
 		if p.lexer.Token.Kind == TkComma {
-			p.lexer.NextToken()
+			p.next()
 		} else if p.lexer.Token.Kind == TkRBracket {
 			option = p.formatOptionName(section, option)
 			c.setOption(option, array)
-			// One option was created or updated.
-			p.optionCount++
-			p.lexer.NextToken()
+			p.next()
 			return nil
 		}
 		skipEOL(p)
 	}
 }
 
-func (p *Parser) convertValue(dstValue interface{}, srcValue *token) (err *ConfigurationError) {
+func (p *Parser) convertValue(dstValue interface{}, srcValue *scanner.Token) (err *ConfigurationError) {
 	if reflect.TypeOf(dstValue) == dateType {
 		if srcValue.Kind == TkDate {
 			return nil
 		}
 		return p.convertValueError("time.Time", srcValue.Kind)
 	}
+	if reflect.TypeOf(dstValue) == durationType {
+		if srcValue.Kind == TkDuration {
+			return nil
+		}
+		return p.convertValueError("time.Duration", srcValue.Kind)
+	}
+	if reflect.TypeOf(dstValue) == sizeType {
+		if srcValue.Kind == TkSize {
+			return nil
+		}
+		return p.convertValueError("config.Size", srcValue.Kind)
+	}
 
 	switch reflect.ValueOf(dstValue).Kind() {
 	case reflect.Bool:
@@ -213,7 +427,7 @@ func (p *Parser) convertValue(dstValue interface{}, srcValue *token) (err *Confi
 		if srcValue.Kind == TkFloat {
 			// Floating point number can be safely converted to an integer?
 			f := srcValue.Value.(float64)
-			if math.Floor(f) == f {
+			if math.Floor(f) == f && f >= math.MinInt64 && f < math.MaxInt64 {
 				srcValue.Kind = TkInt
 				srcValue.Value = int64(f)
 				return nil
@@ -244,34 +458,44 @@ func (p *Parser) convertValue(dstValue interface{}, srcValue *token) (err *Confi
 }
 
 func (p *Parser) formatOptionName(section, option string) string {
-	if section == "" {
-		return option
-	}
-	return section + "." + option
+	return buildOptionPath(section, option)
 }
 
-func (p *Parser) emptySectionError(filename string, t *token) (err *ConfigurationError) {
+// newError returns an error located at given token.
+func (p *Parser) newError(t *scanner.Token, format string, args ...interface{}) *ConfigurationError {
 	return &ConfigurationError{
-		Filename: filename,
-		Line:     t.Line,
-		Column:   t.Column,
-		msg:      fmt.Sprintf("empty section %s", t.Value),
+		Filename:  p.lexer.Filename,
+		Line:      t.Line,
+		Column:    t.Column,
+		EndLine:   t.EndLine,
+		EndColumn: t.EndColumn,
+		Hint:      t.Hint,
+		Code:      ErrSyntax,
+		msg:       fmt.Sprintf(format, args...),
+		source:    p.lexer.SourceLine(t.Line),
 	}
 }
 
+func (p *Parser) emptySectionError(filename string, t *scanner.Token) (err *ConfigurationError) {
+	err = p.newError(t, "empty section %s", t.Value)
+	err.Hint = "sections must declare at least one option"
+	err.Code = ErrEmptySection
+	return err
+}
+
 func (p *Parser) expectedError() (err *ConfigurationError) {
-	return &ConfigurationError{
-		Filename: p.lexer.Filename,
-		Line:     p.lexer.Token.Line,
-		Column:   p.lexer.Token.Column,
-		msg:      "expected section or option declaration",
+	if p.lexer.Token.Kind == TkError {
+		return p.unexpectedError()
 	}
+	return p.newError(&p.lexer.Token, "expected section or option declaration")
 }
 
 func (p *Parser) unexpectedError() (err *ConfigurationError) {
 	var kind string
 
 	switch p.lexer.Token.Kind {
+	case TkError:
+		return p.newError(&p.lexer.Token, "%s", p.lexer.Token.Value)
 	case TkEOF:
 		kind = "end-of-file"
 	case TkEOL:
@@ -289,25 +513,53 @@ func (p *Parser) unexpectedError() (err *ConfigurationError) {
 	case TkDate:
 		date := (p.lexer.Token.Value.(time.Time)).Format(time.RFC3339)
 		kind = fmt.Sprintf("date %s", date)
+	case TkDuration:
+		kind = fmt.Sprintf("duration %s", p.lexer.Token.Value)
+	case TkSize:
+		kind = fmt.Sprintf("size %s", p.lexer.Token.Value)
+	case scanner.TkSecret:
+		kind = SecretRef(p.lexer.Token.Value.(string)).String()
 	case TkEqual, TkLBracket, TkRBracket, TkComma:
 		kind = fmt.Sprintf("character '%s'", p.lexer.Token.Value)
 	default:
 		panic(fmt.Sprintf("unexpected kind %s", kind))
 	}
 
-	return &ConfigurationError{
-		Filename: p.lexer.Filename,
-		Line:     p.lexer.Token.Line,
-		Column:   p.lexer.Token.Column,
-		msg:      fmt.Sprintf("unexpected %s", kind),
+	return p.newError(&p.lexer.Token, "unexpected %s", kind)
+}
+
+func (p *Parser) convertValueError(srcKind string, dstKind scanner.Kind) (err *ConfigurationError) {
+	err = p.newError(&p.lexer.Token, "cannot use type %s as type %s", dstKind, srcKind)
+	err.Hint = "array elements must share the same type"
+	err.Code = ErrTypeMismatch
+	return err
+}
+
+// Error returns the first error of the list and the number of other errors.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
 	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
 }
 
-func (p *Parser) convertValueError(srcKind string, dstKind kind) (err *ConfigurationError) {
-	return &ConfigurationError{
-		Filename: p.lexer.Filename,
-		Line:     p.lexer.Token.Line,
-		Column:   p.lexer.Token.Column,
-		msg:      fmt.Sprintf("cannot use type %s as type %s", dstKind, srcKind),
+// Err returns an error equivalent to the error list, or nil if the list is
+// empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+func (l ErrorList) Len() int      { return len(l) }
+func (l ErrorList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l ErrorList) Less(i, j int) bool {
+	if l[i].Line != l[j].Line {
+		return l[i].Line < l[j].Line
 	}
+	return l[i].Column < l[j].Column
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/cbonello/gp-config"
 	"io/ioutil"
 	. "launchpad.net/gocheck"
+	"math"
 	"os"
 	"time"
 )
@@ -59,7 +60,8 @@ func (pt *ParserTests) TestNewParser1(c *C) {
 	parser, err := config.NewParser("rumpelstilzchen")
 
 	c.Check(parser, IsNil)
-	c.Check(err, IsNil)
+	c.Check(err, NotNil)
+	c.Check(os.IsNotExist(err), Equals, true)
 }
 
 // NewParser(): non-exisiting input file.
@@ -359,6 +361,35 @@ a = [
 	c.Check(value, EqualSlice, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
 }
 
+// Parse(): durations and sizes.
+func (pt *ParserTests) TestPass18(c *C) {
+	contents := `
+[server]
+	timeout = 1m30s
+	retries = [100ms, 1s, 5s]
+	max_body = 10MiB
+	buffers = [4KiB, 64kB]
+`
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	c.Check(pt.parser.Parse(pt.config), IsNil)
+	c.Check(pt.config.Len(), Equals, 4)
+	timeout, err := pt.config.GetDuration("server.timeout")
+	c.Check(err, IsNil)
+	c.Check(timeout, Equals, 90*time.Second)
+	retries, err := pt.config.GetDurationArray("server.retries")
+	c.Check(err, IsNil)
+	c.Check(retries, EqualSlice,
+		[]time.Duration{100 * time.Millisecond, time.Second, 5 * time.Second})
+	maxBody, err := pt.config.GetSize("server.max_body")
+	c.Check(err, IsNil)
+	c.Check(maxBody, Equals, config.Size(10<<20))
+	buffers, err := pt.config.GetSizeArray("server.buffers")
+	c.Check(err, IsNil)
+	c.Check(buffers, EqualSlice, []config.Size{4096, 64000})
+}
+
 // Parse(): parser error.
 func (pt *ParserTests) TestFail1(c *C) {
 	contents := `[`
@@ -432,9 +463,9 @@ func (pt *ParserTests) TestFail6(c *C) {
 	defer pt.cleanTestEnv(c)
 	c.Check(pt.parser, NotNil)
 	err := pt.parser.Parse(pt.config)
-	c.Check(err, ErrorMatches, "unexpected string \"foo\"")
+	c.Check(err, ErrorMatches, "unexpected end-of-file")
 	c.Check(err.Line, Equals, 1)
-	c.Check(err.Column, Equals, 3)
+	c.Check(err.Column, Equals, 10)
 }
 
 // Parse(): parser error.
@@ -878,6 +909,235 @@ func (pt *ParserTests) TestFail39(c *C) {
 	c.Check(err, ErrorMatches, "unexpected '\\*' character")
 }
 
+// Parse(): arrays of durations cannot be mixed with other types.
+func (pt *ParserTests) TestFail40(c *C) {
+	contents := `foo = [1s, 2]`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "cannot use type int64 as type time.Duration")
+}
+
+// Parse(): arrays of sizes cannot be mixed with other types.
+func (pt *ParserTests) TestFail41(c *C) {
+	contents := `foo = [1, 2KiB]`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "cannot use type config.Size as type int64")
+}
+
+// Parse(): out-of-range constants are reported with the option's name.
+func (pt *ParserTests) TestFail42(c *C) {
+	contents := `
+[files]
+	mode = 0o640
+	size = 99999999999999999999`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches,
+		"'files.size': integer constant 99999999999999999999 overflows int64")
+	c.Check(err.Line, Equals, 4)
+	c.Check(err.Column, Equals, 9)
+}
+
+// Parse(): lexer error in an array.
+func (pt *ParserTests) TestFail43(c *C) {
+	contents := `perms = [0o644, 0o79]`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "'perms': invalid digit '9' in octal constant")
+}
+
+// Parse(): lexer error after a value.
+func (pt *ParserTests) TestFail44(c *C) {
+	contents := `foo = 1 *`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "unexpected '\\*' character")
+}
+
+// Parse(): empty quoted keys.
+func (pt *ParserTests) TestFail45(c *C) {
+	contents := `"" = 1`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, NotNil)
+	c.Check(err, ErrorMatches, "expected section or option declaration")
+}
+
+// Parse(): quoted keys.
+func (pt *ParserTests) TestPass19(c *C) {
+	contents := `
+"en-US.utf8" = "English"
+["example.com"]
+	root = "/var/www"
+	"index.html" = true
+[ "plain" ]
+	"a b" = 1
+`
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	c.Check(pt.parser.Parse(pt.config), IsNil)
+	c.Check(pt.config.Len(), Equals, 4)
+	c.Check(pt.config, HasKey, `"en-US.utf8"`)
+	c.Check(pt.config, HasKey, `"example.com".root`)
+	c.Check(pt.config, HasKey, `"example.com"."index.html"`)
+	c.Check(pt.config, HasKey, `plain."a b"`)
+	c.Check(pt.config, HasKey, `"plain"."a b"`)
+	c.Check(pt.config.Sections(), EqualSlice, []string{"", `"example.com"`, "plain"})
+}
+
+// Parse(): inf and nan are floats in value position only.
+func (pt *ParserTests) TestPass20(c *C) {
+	contents := `
+inf = nan
+[nan]
+	inf = [inf, -inf]
+	max = inf
+`
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	c.Check(pt.parser.Parse(pt.config), IsNil)
+	c.Check(pt.config.Sections(), EqualSlice, []string{"", "nan"})
+	v, err := pt.config.GetFloat("inf")
+	c.Check(err, IsNil)
+	c.Check(math.IsNaN(v), Equals, true)
+	c.Check(pt.config.GetFloatDefault("nan.max", 0), Equals, math.Inf(1))
+	c.Check(pt.config, HasKey, "nan.inf")
+
+	f, aerr := config.NewStringParser(contents).ParseAST()
+	c.Assert(aerr, IsNil)
+	c.Check(f.Options[0].Key.Name, Equals, "inf")
+	c.Check(f.Sections[0].Name.Name, Equals, "nan")
+}
+
+// Parse(): sections cannot be nested.
+func (pt *ParserTests) TestFail46(c *C) {
+	contents := `
+[a]
+[b]
+x = 1`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	err := pt.parser.Parse(pt.config)
+	c.Check(err, ErrorMatches, "empty section a")
+	c.Check(pt.config.Len(), Equals, 0)
+}
+
+// ParseAll(): valid configuration.
+func (pt *ParserTests) TestParseAll1(c *C) {
+	contents := `
+a = 1
+[b]
+c = "d"`
+
+	pt.createTestEnv(c, contents)
+	defer pt.cleanTestEnv(c)
+	c.Check(pt.parser, NotNil)
+	errs := pt.parser.ParseAll(pt.config, false)
+	c.Check(errs, HasLen, 0)
+	c.Check(errs.Err(), IsNil)
+	c.Check(pt.config.Len(), Equals, 2)
+	c.Check(pt.config, HasKey, "b.c")
+}
+
+// ParseAll(): all errors are reported and configuration is left untouched.
+func (pt *ParserTests) TestParseAll2(c *C) {
+	contents := `
+a = 1
+b = 'single quotes'
+[c]
+d = 0x
+e = 2
+[f
+g = [1, "two"]
+h = *
+`
+
+	pt.config = config.NewConfiguration()
+	pt.parser = config.NewStringParser(contents)
+	errs := pt.parser.ParseAll(pt.config, false)
+	c.Assert(errs, HasLen, 5)
+	c.Check(errs[0].Line, Equals, 3)
+	c.Check(errs[0], ErrorMatches, "'b': unexpected ''' character")
+	c.Check(errs[1].Line, Equals, 5)
+	c.Check(errs[1], ErrorMatches, "'c.d': malformed hex constant \"0x\"")
+	c.Check(errs[2].Line, Equals, 7)
+	c.Check(errs[2], ErrorMatches, "unexpected end-of-line")
+	c.Check(errs[3].Line, Equals, 8)
+	c.Check(errs[3], ErrorMatches, "cannot use type string as type int64")
+	c.Check(errs[4].Line, Equals, 9)
+	c.Check(errs.Error(), Equals,
+		"'b': unexpected ''' character (and 4 more errors)")
+	c.Check(pt.config.Len(), Equals, 0)
+}
+
+// ParseAll(): valid options of an invalid configuration are applied on
+// request.
+func (pt *ParserTests) TestParseAll3(c *C) {
+	contents := `
+a = 1
+b = *
+[c]
+d = true`
+
+	pt.config = config.NewConfiguration()
+	c.Check(pt.config.LoadString("a = 0\nz = 26"), IsNil)
+	pt.parser = config.NewStringParser(contents)
+	errs := pt.parser.ParseAll(pt.config, true)
+	c.Check(errs, HasLen, 1)
+	c.Check(errs.Err(), NotNil)
+	c.Check(pt.config.Len(), Equals, 3)
+	c.Check(pt.config.GetIntDefault("a", 0), Equals, int64(1))
+	c.Check(pt.config.GetIntDefault("z", 0), Equals, int64(26))
+	c.Check(pt.config.GetBoolDefault("c.d", false), Equals, true)
+	c.Check(pt.config.String(), Equals, "a = 1\nz = 26\nc.d = true\n")
+}
+
+// ParseAll(): parser resumes the current section after an error.
+func (pt *ParserTests) TestParseAll4(c *C) {
+	pt.config = config.NewConfiguration()
+	pt.parser = config.NewStringParser("[c]\nd = *\ne = 2\n")
+	errs := pt.parser.ParseAll(pt.config, true)
+	c.Assert(errs, HasLen, 1)
+	c.Check(errs[0], ErrorMatches, "'c.d': unexpected '\\*' character")
+	c.Check(pt.config.Sections(), EqualSlice, []string{"c"})
+	c.Check(pt.config.String(), Equals, "c.e = 2\n")
+
+	pt.config = config.NewConfiguration()
+	pt.config.SetProfile("dev")
+	pt.parser = config.NewStringParser("[c@dev]\nd = *\ne = 2\n[c]\ne = 1\nf = *\ng = 3")
+	errs = pt.parser.ParseAll(pt.config, true)
+	c.Assert(errs, HasLen, 2)
+	c.Check(pt.config.String(), Equals, "c.e = 2\nc.g = 3\n")
+}
+
 // HasKey checks whether the configuration dictionnary records a given key
 // or not.
 type hasKeyChecker struct {
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"github.com/cbonello/gp-config/scanner"
+	"strings"
+)
+
+// JoinPath builds the path of an option from its section and option names.
+// Names that are not valid identifiers (e.g. "example.com") are quoted so
+// that the path can be given to Get, HasOption, Options and the other path
+// APIs:
+//
+//	JoinPath("example.com", "port") == `"example.com".port`
+func JoinPath(keys ...string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = quoteKey(k)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// SplitPath splits a dot-separated option path into section and option
+// names. Quotes around names are removed.
+func SplitPath(path string) ([]string, error) {
+	keys := []string{}
+	for i := 0; ; {
+		key := ""
+		if i < len(path) && path[i] == '"' {
+			// Quoted key; '\' escapes the next character.
+			i++
+			for {
+				if i >= len(path) {
+					return nil, fmt.Errorf("'%s': unterminated quoted key", path)
+				}
+				if path[i] == '\\' && i+1 < len(path) {
+					i++
+				} else if path[i] == '"' {
+					i++
+					break
+				}
+				key += path[i : i+1]
+				i++
+			}
+			if key == "" {
+				return nil, fmt.Errorf("'%s': empty key", path)
+			}
+		} else {
+			j := strings.IndexAny(path[i:], ".\"")
+			if j == -1 {
+				j = len(path) - i
+			}
+			key = path[i : i+j]
+			i += j
+			if key == "" {
+				return nil, fmt.Errorf("'%s': empty key", path)
+			}
+		}
+		keys = append(keys, key)
+		if i == len(path) {
+			return keys, nil
+		}
+		if path[i] != '.' {
+			return nil, fmt.Errorf("'%s': unexpected character '%c' after key",
+				path, path[i])
+		}
+		i++
+	}
+}
+
+// isBareKey returns true if key can be written without quotes; that is, if
+// the lexer would read it as an identifier.
+func isBareKey(key string) bool {
+	return scanner.IsIdentifier(key)
+}
+
+func quoteKey(key string) string {
+	if isBareKey(key) {
+		return key
+	}
+	key = strings.Replace(key, "\\", "\\\\", -1)
+	key = strings.Replace(key, "\"", "\\\"", -1)
+	return "\"" + key + "\""
+}
+
+// canonicalPath returns given option path with names quoted only if needed.
+// Malformed paths are returned unchanged; they do not match any option.
+func canonicalPath(path string) string {
+	if strings.IndexByte(path, '"') == -1 {
+		return path
+	}
+	keys, err := SplitPath(path)
+	if err != nil {
+		return path
+	}
+	return JoinPath(keys...)
+}
@@ -0,0 +1,80 @@
+package config_test
+
+import (
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+)
+
+type (
+	PathTests struct{}
+)
+
+var (
+	_ = Suite(&PathTests{})
+)
+
+// JoinPath(): bare and quoted keys.
+func (pt *PathTests) TestJoinPath1(c *C) {
+	c.Check(config.JoinPath(), Equals, "")
+	c.Check(config.JoinPath("server", "port"), Equals, "server.port")
+	c.Check(config.JoinPath("example.com", "port"), Equals, `"example.com".port`)
+	c.Check(config.JoinPath("a\"b", "c\\d"), Equals, `"a\"b"."c\\d"`)
+	c.Check(config.JoinPath("1st", "x y"), Equals, `"1st"."x y"`)
+}
+
+// SplitPath(): valid paths.
+func (pt *PathTests) TestSplitPath1(c *C) {
+	keys, err := config.SplitPath("server.port")
+	c.Check(err, IsNil)
+	c.Check(keys, EqualSlice, []string{"server", "port"})
+	keys, err = config.SplitPath(`"example.com"."index.html"`)
+	c.Check(err, IsNil)
+	c.Check(keys, EqualSlice, []string{"example.com", "index.html"})
+	keys, err = config.SplitPath(`"a\"b".c`)
+	c.Check(err, IsNil)
+	c.Check(keys, EqualSlice, []string{"a\"b", "c"})
+}
+
+// SplitPath(): malformed paths.
+func (pt *PathTests) TestSplitPath2(c *C) {
+	_, err := config.SplitPath(`"example.com`)
+	c.Check(err, ErrorMatches, "'\"example.com': unterminated quoted key")
+	_, err = config.SplitPath("server..port")
+	c.Check(err, ErrorMatches, "'server..port': empty key")
+	_, err = config.SplitPath(`"a"b`)
+	c.Check(err, ErrorMatches, "'\"a\"b': unexpected character 'b' after key")
+	_, err = config.SplitPath(`"".a`)
+	c.Check(err, ErrorMatches, "'\"\".a': empty key")
+}
+
+// Get(), Options() and Decode() with quoted keys.
+func (pt *PathTests) TestQuotedKeys1(c *C) {
+	contents := `
+["example.com"]
+	root = "/var/www"
+	"max.conn" = 10
+[example]
+	root = "/srv"`
+
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(contents), IsNil)
+
+	root, err := cfg.GetString(config.JoinPath("example.com", "root"))
+	c.Check(err, IsNil)
+	c.Check(root, Equals, "/var/www")
+	c.Check(cfg.IsSection(`"Example.com"`), Equals, true)
+	c.Check(cfg.Options(`"example.com"`), EqualSlice,
+		[]string{`"example.com"."max.conn"`, `"example.com".root`})
+	c.Check(cfg.Options("example"), EqualSlice, []string{"example.root"})
+
+	type (
+		host struct {
+			Root    string
+			MaxConn int64 `option:"max.conn"`
+		}
+	)
+	h := host{}
+	c.Check(cfg.Decode(`"example.com"`, &h), IsNil)
+	c.Check(h.Root, Equals, "/var/www")
+	c.Check(h.MaxConn, Equals, int64(10))
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"github.com/cbonello/gp-config/scanner"
+	"strings"
+)
+
+// ProfileEnv is the name of the environment variable giving the profile
+// active in new configurations (see SetProfile).
+const ProfileEnv = "GPCONFIG_PROFILE"
+
+// SetProfile sets the active profile. Sections named section@profile are
+// profile sections: when their profile is active, their options override
+// the ones of the base section once the configuration they belong to is
+// loaded, whatever the order of declaration; otherwise, they are ignored and
+// their secrets and encrypted values are not resolved.
+//
+//	[database]
+//	    host = "db.example.com"
+//	[database@dev]
+//	    host = "localhost"
+//
+// Profile names are case insensitive unless section names are. Only
+// configurations loaded afterwards are affected; an empty string disables
+// profiles.
+func (c *Configuration) SetProfile(profile string) {
+	if c != nil {
+		c.Lock()
+		defer c.Unlock()
+		c.profile = profile
+	}
+}
+
+// Profile returns the active profile, or an empty string if none.
+func (c *Configuration) Profile() (profile string) {
+	if c != nil {
+		c.RLock()
+		defer c.RUnlock()
+		profile = c.profile
+	}
+	return profile
+}
+
+// Origin returns the path of the declaration that supplied the value of
+// given option: database@dev.host if it was supplied by the profile section
+// of profile dev, database.host otherwise.
+func (c *Configuration) Origin(option string) (string, error) {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			if opt.profile == "" {
+				return opt.name, nil
+			}
+			names, _ := SplitPath(opt.name)
+			if len(names) == 1 {
+				// Global option.
+				names = []string{"", names[0]}
+			}
+			names[0] += "@" + opt.profile
+			return JoinPath(names...), nil
+		}
+	}
+	return "", unknownOptionError(option)
+}
+
+// isActiveProfile returns true if given profile is the active one. Caller
+// must hold the lock.
+func (c *Configuration) isActiveProfile(profile string) bool {
+	return c.profile != "" && c.key(profile) == c.key(c.profile)
+}
+
+// resolves returns true if secrets and encrypted values of given option are
+// resolved when loaded: secrets are resolved, and option is not declared in
+// the profile section of an inactive profile, which merge ignores.
+// Credentials of all environments may so be declared side by side.
+func (c *Configuration) resolves(option string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	if c.rawSecrets {
+		return false
+	}
+	if _, profile, isProfile := profileOption(option); isProfile {
+		return c.isActiveProfile(profile)
+	}
+	return true
+}
+
+// profileSection splits the name of a profile section into the names of its
+// base section and of its profile. Names of profile sections are made of two
+// identifiers separated by '@', the first one being empty for global
+// options; other names holding '@' (e.g. "admin@example.com") are ordinary
+// section names.
+func profileSection(name string) (section, profile string, ok bool) {
+	i := strings.IndexByte(name, '@')
+	if i == -1 {
+		return "", "", false
+	}
+	section, profile = name[:i], name[i+1:]
+	if strings.IndexByte(profile, '@') != -1 || scanner.IsIdentifier(profile) == false {
+		return "", "", false
+	}
+	if section != "" && scanner.IsIdentifier(section) == false {
+		return "", "", false
+	}
+	return section, profile, true
+}
+
+// profileOption returns the path of an option declared in a profile section
+// in its base section, and the profile of the section.
+func profileOption(path string) (option, profile string, ok bool) {
+	if strings.IndexByte(path, '@') == -1 {
+		return "", "", false
+	}
+	names, err := SplitPath(path)
+	if err != nil || len(names) != 2 {
+		return "", "", false
+	}
+	section, profile, ok := profileSection(names[0])
+	if ok == false {
+		return "", "", false
+	}
+	if section == "" {
+		// Profile section of global options: ["@dev"].
+		return JoinPath(names[1]), profile, true
+	}
+	return JoinPath(section, names[1]), profile, true
+}
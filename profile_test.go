@@ -0,0 +1,147 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"os"
+	"strings"
+)
+
+type ProfileTests struct{}
+
+var (
+	_ = Suite(&ProfileTests{})
+)
+
+const profiles = `
+name = "app"
+
+[database@DEV]
+	host = "localhost"
+	debug = true
+
+[database]
+	host = "db.example.com"
+	port = 5432
+
+[database@test]
+	host = "test.example.com"
+
+["@dev"]
+	name = "app-dev"
+`
+
+// SetProfile(), Origin().
+func (pt *ProfileTests) TestSetProfile1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(profiles), IsNil)
+	c.Check(cfg.Profile(), Equals, "")
+	c.Check(cfg.String(), Equals, `name = "app"
+database.host = "db.example.com"
+database.port = 5432
+`)
+
+	cfg = config.NewConfiguration()
+	cfg.SetProfile("dev")
+	c.Check(cfg.Profile(), Equals, "dev")
+	c.Assert(cfg.LoadString(profiles), IsNil)
+	c.Check(cfg.String(), Equals, `name = "app-dev"
+database.host = "localhost"
+database.port = 5432
+database.debug = true
+`)
+	c.Check(cfg.Sections(), DeepEquals, []string{"", "database"})
+	origins := map[string]string{
+		"name":          `"@dev".name`,
+		"database.host": "database@DEV.host",
+		"database.port": "database.port",
+	}
+	for option, expected := range origins {
+		origin, err := cfg.Origin(option)
+		c.Check(err, IsNil)
+		c.Check(origin, Equals, expected)
+	}
+	_, err := cfg.Origin("database.user")
+	c.Check(errors.Is(err, config.ErrUnknownOption), Equals, true)
+
+	// Options declared afterwards override the ones of profiles.
+	c.Assert(cfg.LoadString("[database]\nhost = \"db.local\""), IsNil)
+	c.Check(cfg.Set("name", "app"), IsNil)
+	origin, _ := cfg.Origin("database.host")
+	c.Check(origin, Equals, "database.host")
+	c.Check(cfg.GetStringDefault("name", ""), Equals, "app")
+}
+
+// SetProfile(): environment variable, other formats.
+func (pt *ProfileTests) TestSetProfile2(c *C) {
+	os.Setenv(config.ProfileEnv, "test")
+	cfg := config.NewConfiguration()
+	os.Unsetenv(config.ProfileEnv)
+	c.Check(cfg.Profile(), Equals, "test")
+	c.Assert(cfg.LoadJSON("app.json", strings.NewReader(`{"database@test": {"port": 5433}, "database": {"port": 5432}}`)), IsNil)
+	c.Assert(cfg.LoadINI("app.ini", strings.NewReader("[database@dev]\nport = 5434")), IsNil)
+	c.Check(cfg.GetIntDefault("database.port", 0), Equals, int64(5433))
+
+	// Errors are reported in profile sections that are not active.
+	err := cfg.LoadString("[database@dev]\nport = ")
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+	errs := config.NewStringParser("[database@test]\nport = 1\nhost = ").ParseAll(cfg, true)
+	c.Check(errs, HasLen, 1)
+	c.Check(cfg.GetIntDefault("database.port", 0), Equals, int64(1))
+}
+
+// SetProfile(): only sections named identifier@identifier are profile
+// sections.
+func (pt *ProfileTests) TestSetProfile3(c *C) {
+	cfg := config.NewConfiguration()
+	cfg.SetProfile("example.com")
+	c.Assert(cfg.LoadString("[\"admin@example.com\"]\nquota = 10"), IsNil)
+	c.Assert(cfg.LoadJSON("app.json", strings.NewReader(`{"user@example.com": {"quota": 20}, "@": {"a": 1}}`)), IsNil)
+	c.Check(cfg.Sections(), DeepEquals, []string{`"@"`, `"admin@example.com"`, `"user@example.com"`})
+	quota, err := cfg.GetInt(`"admin@example.com".quota`)
+	c.Check(err, IsNil)
+	c.Check(quota, Equals, int64(10))
+	c.Check(cfg.GetIntDefault(`"user@example.com".quota`, 0), Equals, int64(20))
+	c.Check(cfg.GetIntDefault(`"@".a`, 0), Equals, int64(1))
+	origin, _ := cfg.Origin(`"admin@example.com".quota`)
+	c.Check(origin, Equals, `"admin@example.com".quota`)
+}
+
+// SetProfile(): secrets and encrypted values of inactive profiles are not
+// resolved.
+func (pt *ProfileTests) TestSetProfile4(c *C) {
+	encrypted, err := config.Encrypt("s3cr3t", testKey)
+	c.Assert(err, IsNil)
+	os.Setenv("GPCONFIG_TEST_TOKEN", "t0k3n")
+	defer os.Unsetenv("GPCONFIG_TEST_TOKEN")
+	contents := `[db]
+	token = secret("env:GPCONFIG_TEST_TOKEN")
+[db@dev]
+	token = "dev"
+[db@prod]
+	token = secret("file:/nonexistent/secrets/prod-db")
+	password = "` + encrypted + `"
+`
+
+	cfg := config.NewConfiguration()
+	cfg.SetProfile("dev")
+	c.Assert(cfg.LoadString(contents), IsNil)
+	c.Check(cfg.GetStringDefault("db.token", ""), Equals, "dev")
+	c.Check(cfg.HasOption("db.password"), Equals, false)
+	c.Check(config.NewStringParser(contents).ParseAll(cfg, false), HasLen, 0)
+	c.Assert(cfg.LoadJSON("app.json", strings.NewReader(`{"db@prod": {"password": "`+encrypted+`"}}`)), IsNil)
+	c.Check(cfg.HasOption("db.password"), Equals, false)
+
+	// Active profiles are resolved.
+	cfg = config.NewConfiguration()
+	cfg.SetProfile("prod")
+	err = cfg.LoadString(contents)
+	c.Check(errors.Is(err, config.ErrSecret), Equals, true)
+	c.Check(err, ErrorMatches, "'db@prod.token': open /nonexistent/secrets/prod-db: no such file or directory")
+	cfg.SetKeyProvider(config.StaticKey(testKey))
+	c.Assert(cfg.LoadString(strings.Replace(contents, "file:/nonexistent/secrets/prod-db", "env:GPCONFIG_TEST_TOKEN", 1)), IsNil)
+	c.Check(cfg.GetStringDefault("db.token", ""), Equals, "t0k3n")
+	c.Check(cfg.GetStringDefault("db.password", ""), Equals, "s3cr3t")
+	c.Check(cfg.IsSecret("db.password"), Equals, true)
+}
@@ -0,0 +1,1045 @@
+// Package scanner implements the lexical scanner of gp-config configuration
+// files. It is used by the configuration parser and may be used by tools
+// (formatters, linters, editors) that need to work at the token level:
+//
+//	s := scanner.New("app.cfg", contents)
+//	for s.NextToken(); s.Token.Kind != scanner.TkEOF; s.NextToken() {
+//	    if s.Token.Kind == scanner.TkError {
+//	        ...
+//	    }
+//	    fmt.Println(s.Token)
+//	}
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	// Kind identifies the kind of a token.
+	Kind int8
+
+	// Mode controls the scanner behavior.
+	Mode uint
+
+	// Position is a position in input. Lines and columns start at 1; columns
+	// are byte offsets in line.
+	Position struct {
+		Line   int
+		Column int
+	}
+
+	char struct {
+		offset     int
+		line       int
+		column     int
+		lineOffset int // Offset of first rune of line.
+		r          rune
+	}
+
+	// Token is a lexical token.
+	Token struct {
+		Kind      Kind
+		Line      int
+		Column    int
+		EndLine   int // End of token (exclusive).
+		EndColumn int
+		Raw       string // Token as written in input.
+		// Decoded value: string for identifiers, strings (quotes removed,
+		// escape sequences kept), comments (text following '#') and errors
+		// (error message), bool, int64, float64, time.Time, time.Duration and
+		// int64 for sizes (number of bytes).
+		Value interface{}
+		Hint  string // Hint to fix a lexical error, may be empty.
+	}
+
+	// Scanner reads tokens from a string or an io.Reader.
+	Scanner struct {
+		Filename   string
+		Mode       Mode
+		contents   string    // Input of string scanners.
+		reader     io.Reader // Input of stream scanners; nil for string scanners.
+		buf        []byte    // Window on input of stream scanners.
+		base       int       // Offset of buf[0] in input.
+		keep       int       // Input from this offset on must stay in buf.
+		keepLine   int       // Line starting at keep, 0 if none.
+		tokStart   int       // Start of token being read, noToken if none.
+		err        error     // Error returned by reader.
+		inputLen   int       // Length of input read so far.
+		offset     int       // Start of last rune read from input.
+		width      int       // Width of last rune read from input.
+		line       int       // Line of last rune declaration.
+		column     int       // Column of last rune declaration.
+		lineOffset int       // Offset of first rune of line.
+		c          char      // Last rune read.
+		Token      Token     // Last token read.
+	}
+)
+
+// Tokens returned by scanner.
+const (
+	TkEOF        Kind = iota // End-of-file token.
+	TkEOL                    // End-of-line token.
+	TkError                  // An error occurred; value is error message.
+	TkIdentifier             // Identifier token.
+	TkBool                   // Boolean.
+	TkString                 // A string (does not include double quotes).
+	TkInt                    // An integer.
+	TkFloat                  // A floating point number.
+	TkDate                   // A date.
+	TkEqual                  // '='.
+	TkLBracket               // '['.
+	TkRBracket               // ']'.
+	TkComma                  // ','.
+	TkDuration               // A duration (1m30s).
+	TkSize                   // A size in bytes (10MiB).
+	TkComment                // A comment; only returned in ScanComments mode.
+	TkSecret                 // A secret reference; value is the reference.
+
+	_EOF = -1
+)
+
+// Scanner modes.
+const (
+	// ScanComments makes the scanner return comments instead of skipping
+	// them.
+	ScanComments Mode = 1 << iota
+)
+
+const (
+	// Initial size of stream scanners buffer.
+	streamBufSize = 64 << 10
+	// Longest line kept in memory by stream scanners to report errors.
+	maxSourceLine = 64 << 10
+	// Length of longest date (9999-12-31T23:59:59Z).
+	dateLen = 20
+	// tokStart value outside of tokens.
+	noToken = -1
+)
+
+var (
+	// To parse a date in zulu form (RFC 3339 format).
+	dateRe = regexp.MustCompile("^\\d{1,4}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2}Z")
+	// To parse a size: unsigned integer or decimal number and a unit.
+	sizeRe = regexp.MustCompile("^\\+?(\\d+(?:\\.\\d+)?)([a-zA-Z]+)$")
+
+	// Names of integer bases used in error messages.
+	baseNames = map[int]string{2: "binary", 8: "octal", 16: "hex"}
+
+	// Size units sorted in descending order of magnitude.
+	sizeUnits = []struct {
+		name   string
+		factor int64
+	}{
+		{"PiB", 1 << 50}, {"PB", 1e15},
+		{"TiB", 1 << 40}, {"TB", 1e12},
+		{"GiB", 1 << 30}, {"GB", 1e9},
+		{"MiB", 1 << 20}, {"MB", 1e6},
+		{"KiB", 1 << 10}, {"kB", 1e3},
+		{"B", 1},
+	}
+)
+
+// New instanciates a scanner reading given string.
+func New(filename, contents string) *Scanner {
+	l := &Scanner{
+		Filename: filename,
+		contents: contents,
+		inputLen: len(contents),
+		offset:   0,
+		width:    0,
+		line:     1,
+		column:   1,
+	}
+	l.nextRune()
+	return l
+}
+
+// NewReader instanciates a scanner reading its input from r. Only a window
+// on the input is kept in memory: the current token and, for error
+// reporting, the line it belongs to if that line is not too long.
+func NewReader(filename string, r io.Reader) *Scanner {
+	l := &Scanner{
+		Filename: filename,
+		reader:   r,
+		buf:      make([]byte, 0, streamBufSize),
+		line:     1,
+		column:   1,
+		tokStart: noToken,
+	}
+	l.nextRune()
+	return l
+}
+
+// Err returns the error returned by the reader of a stream scanner, if any.
+// Scanner reports end-of-file after a read error.
+func (l *Scanner) Err() error {
+	if l.err == io.EOF {
+		return nil
+	}
+	return l.err
+}
+
+// NextToken reads the next token into l.Token.
+func (l *Scanner) NextToken() {
+	l.nextToken()
+	// Token ends where next rune starts. Errors are reported at a single
+	// rune when no better span is known.
+	l.Token.EndLine, l.Token.EndColumn = l.c.line, l.c.column
+	if l.Token.EndLine != l.Token.Line || l.Token.EndColumn <= l.Token.Column {
+		l.Token.EndLine, l.Token.EndColumn = l.Token.Line, l.Token.Column+1
+	}
+	l.Token.Raw = ""
+	if l.tokStart != noToken {
+		l.Token.Raw = l.text(l.tokStart, l.c.offset)
+	}
+}
+
+// Pos returns the position of the first character of token.
+func (t Token) Pos() Position {
+	return Position{t.Line, t.Column}
+}
+
+// End returns the position following token.
+func (t Token) End() Position {
+	return Position{t.EndLine, t.EndColumn}
+}
+
+// IsValid returns true if position is set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// IsIdentifier returns true if s would be read as an identifier; that is,
+// if it may be used as a section or option name without quotes. Names of
+// profile sections (e.g. database@dev) are made of two identifiers
+// separated by '@'.
+func IsIdentifier(s string) bool {
+	if i := strings.IndexByte(s, '@'); i != -1 {
+		return isName(s[:i]) && isName(s[i+1:])
+	}
+	return isName(s)
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	if unicode.IsLetter(r) == false && r != '_' {
+		return false
+	}
+	for _, r := range s {
+		if isAlphaNumeric(r) == false {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatSize formats a number of bytes using the largest unit that
+// represents it exactly (e.g. 10MiB, 64kB or 1000B). Output is a valid size
+// literal.
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if n != 0 && n%u.factor == 0 {
+			return fmt.Sprintf("%d%s", n/u.factor, u.name)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+func (l *Scanner) nextToken() {
+	for {
+		l.skipWhitespaces()
+		if l.c.r == '#' && l.Mode&ScanComments == 0 {
+			l.skipComment()
+		} else {
+			l.mark()
+			switch {
+			case unicode.IsLetter(l.c.r) || l.c.r == '_':
+				l.parseIdentifier()
+			case l.c.r == '"':
+				l.parseString()
+			case l.c.r == '#':
+				l.parseComment()
+			case unicode.IsDigit(l.c.r):
+				if l.parseDate() == false {
+					l.parseNumber()
+				}
+			case l.c.r == '-', l.c.r == '+':
+				l.parseNumber()
+			case l.c.r == '=':
+				defer l.nextRune()
+				l.setToken(TkEqual, l.c.line, l.c.column, "=")
+			case l.c.r == '[':
+				defer l.nextRune()
+				l.setToken(TkLBracket, l.c.line, l.c.column, "[")
+			case l.c.r == ']':
+				defer l.nextRune()
+				l.setToken(TkRBracket, l.c.line, l.c.column, "]")
+			case l.c.r == ',':
+				defer l.nextRune()
+				l.setToken(TkComma, l.c.line, l.c.column, ",")
+			case l.c.r == '\n':
+				defer l.nextRune()
+				l.setToken(TkEOL, l.c.line, l.c.column, nil)
+			case l.c.r == _EOF:
+				l.setToken(TkEOF, l.c.line, l.c.column, nil)
+			default:
+				if unicode.IsPrint(l.c.r) {
+					l.setErrorToken("unexpected '%c' character",
+						l.c.r)
+					if l.c.r == '\'' {
+						l.Token.Hint = "strings must be double-quoted"
+					}
+				} else {
+					l.setErrorToken("unexpected \\u%04X character",
+						l.c.r)
+				}
+			}
+			break
+		}
+	}
+}
+
+func isSpace(r rune) bool {
+	switch r {
+	case '\t', '\v', '\f', ' ', 0x85, 0xA0:
+		return true
+	}
+	return false
+}
+
+func isEOL(r rune) bool {
+	return r == '\n' || r == '\r'
+}
+
+func isHexadecimal(r rune) bool {
+	return unicode.IsNumber(r) ||
+		(r >= 'a' && r <= 'f') ||
+		(r >= 'A' && r <= 'F')
+}
+
+func isAlphaNumeric(r rune) bool {
+	alphaDigitSet := []*unicode.RangeTable{unicode.L, unicode.N}
+	return unicode.IsOneOf(alphaDigitSet, r) || r == '_' || r == '-'
+}
+
+// Returns and consumes the next rune.
+func (l *Scanner) nextRune() {
+	l.c.offset = l.offset
+	l.c.line = l.line
+	l.c.column = l.column
+	l.c.lineOffset = l.lineOffset
+	if l.reader != nil && l.inputLen-l.offset < utf8.UTFMax {
+		l.fill(utf8.UTFMax)
+	}
+	if l.offset >= l.inputLen {
+		l.c.r = _EOF
+	} else {
+		if l.reader == nil {
+			l.c.r, l.width = utf8.DecodeRuneInString(l.contents[l.offset:])
+		} else {
+			l.c.r, l.width = utf8.DecodeRune(l.buf[l.offset-l.base:])
+		}
+		if l.c.r == '\n' {
+			l.line++
+			l.column = 0
+			l.lineOffset = l.offset + l.width
+		}
+		l.offset += l.width
+		l.column += l.width
+	}
+}
+
+// fill reads input until n bytes following the current offset are available
+// or the end of input is reached. It does nothing for string lexers.
+func (l *Scanner) fill(n int) {
+	for l.reader != nil && l.err == nil && l.inputLen-l.offset < n {
+		if len(l.buf) == cap(l.buf) {
+			l.compact()
+		}
+		m, err := l.reader.Read(l.buf[len(l.buf):cap(l.buf)])
+		l.buf = l.buf[:len(l.buf)+m]
+		l.inputLen += m
+		if err != nil {
+			l.err = err
+		}
+	}
+}
+
+// compact discards the input preceding keep to make room in buf, which is
+// grown if more than half of it is still in use.
+func (l *Scanner) compact() {
+	if l.offset-l.keep > maxSourceLine {
+		// Line is too long to be kept; only keep current token.
+		l.keep, l.keepLine = l.tokStart, 0
+		if l.tokStart == noToken {
+			l.keep = l.c.offset
+		}
+	}
+	if n := l.keep - l.base; n > 0 {
+		copy(l.buf, l.buf[n:])
+		l.buf = l.buf[:len(l.buf)-n]
+		l.base = l.keep
+	}
+	if 2*len(l.buf) > cap(l.buf) {
+		buf := make([]byte, len(l.buf), 2*cap(l.buf))
+		copy(buf, l.buf)
+		l.buf = buf
+	}
+}
+
+// mark records the start of a token. Stream lexers keep the token and its
+// line in memory until next token.
+func (l *Scanner) mark() {
+	l.tokStart = l.c.offset
+	l.keep, l.keepLine = l.c.lineOffset, l.c.line
+}
+
+// text returns the input between given offsets.
+func (l *Scanner) text(from, to int) string {
+	if l.reader == nil {
+		return l.contents[from:to]
+	}
+	return string(l.buf[from-l.base : to-l.base])
+}
+
+// byteAt returns the byte of input at given offset, which must not precede
+// the current rune, or -1 at end of input.
+func (l *Scanner) byteAt(offset int) int {
+	l.fill(offset + 1 - l.offset)
+	if offset >= l.inputLen {
+		return -1
+	}
+	if l.reader == nil {
+		return int(l.contents[offset])
+	}
+	return int(l.buf[offset-l.base])
+}
+
+// Consumes the next rune if it's from the valid set.
+func (l *Scanner) acceptOneRune(valid string) bool {
+	if strings.IndexRune(valid, l.c.r) >= 0 {
+		l.nextRune()
+		return true
+	}
+	return false
+}
+
+// Consumes as many runes as we can from the valid set.
+// The count determines the number of runes to consume:
+//
+//	n < 0: consume as many runes as we can.
+//	n = 0: do nothing!
+//	n > 0: consumes exactly n runes.
+func (l *Scanner) acceptManyRunes(valid string, n int) (consumed int) {
+	switch {
+	case n < 0:
+		for l.acceptOneRune(valid) {
+			consumed++
+		}
+	case n > 0:
+		for l.acceptOneRune(valid) {
+			consumed++
+			if n = n - 1; n <= 0 {
+				break
+			}
+		}
+	}
+	return consumed
+}
+
+// Consumes a sequence of digits from the valid set, optionally separated by
+// underscores (1_000_000). Returns the number of digits consumed; ok is false
+// if an underscore does not separate two digits.
+func (l *Scanner) acceptDigits(valid string) (consumed int, ok bool) {
+	prev := rune(_EOF)
+	ok = true
+	for {
+		if l.c.r == '_' {
+			if prev == _EOF || prev == '_' {
+				ok = false
+			}
+		} else if strings.IndexRune(valid, l.c.r) >= 0 {
+			consumed++
+		} else {
+			break
+		}
+		prev = l.c.r
+		l.nextRune()
+	}
+	if prev == '_' {
+		ok = false
+	}
+	return consumed, ok
+}
+
+// Returns but does not consume the next rune.
+func (l *Scanner) peekRune() (r rune) {
+	if l.reader == nil {
+		r, _ = utf8.DecodeRuneInString(l.contents[l.offset:])
+	} else {
+		l.fill(utf8.UTFMax)
+		r, _ = utf8.DecodeRune(l.buf[l.offset-l.base:])
+	}
+	return r
+}
+
+// Skip N runes.
+func (l *Scanner) skipRune(n int) {
+	for n > 0 {
+		l.nextRune()
+		if l.c.r == _EOF {
+			break
+		}
+		n--
+	}
+}
+
+func (l *Scanner) setErrorToken(format string, args ...interface{}) {
+	l.Token.Kind = TkError
+	l.Token.Line = l.c.line
+	l.Token.Column = l.c.column
+	l.Token.Value = fmt.Sprintf(format, args...)
+	l.Token.Hint = ""
+	//fmt.Printf("TOKEN %s\n", l.Token)
+}
+
+func (l *Scanner) setToken(k Kind, line, column int, value interface{}) {
+	l.Token.Kind = k
+	l.Token.Line = line
+	l.Token.Column = column
+	l.Token.Value = value
+	l.Token.Hint = ""
+	//fmt.Printf("TOKEN %s\n", l.Token)
+}
+
+func (l *Scanner) skipWhitespaces() {
+	if isSpace(l.c.r) {
+		l.tokStart = noToken
+		for {
+			if l.nextRune(); isSpace(l.c.r) == false {
+				break
+			}
+		}
+	}
+}
+
+// Skips runes up to the next end-of-line; used to recover from errors.
+func (l *Scanner) SkipLine() {
+	l.tokStart = noToken
+	for l.c.r != '\n' && l.c.r != _EOF {
+		l.nextRune()
+	}
+}
+
+func (l *Scanner) skipComment() {
+	l.tokStart = noToken
+	for {
+		if l.nextRune(); isEOL(l.c.r) || l.c.r == _EOF {
+			break
+		}
+	}
+}
+
+func (l *Scanner) parseComment() {
+	start := l.c
+	for {
+		if l.nextRune(); isEOL(l.c.r) || l.c.r == _EOF {
+			break
+		}
+	}
+	l.setToken(TkComment, start.line, start.column,
+		l.text(start.offset+1, l.c.offset))
+}
+
+func (l *Scanner) parseIdentifier() {
+	start := l.c
+	profile := false
+	for {
+		if l.nextRune(); isAlphaNumeric(l.c.r) {
+			continue
+		}
+		// Profile sections are named section@profile.
+		if l.c.r == '@' && profile == false {
+			if r := l.peekRune(); unicode.IsLetter(r) || r == '_' {
+				profile = true
+				continue
+			}
+		}
+		break
+	}
+	id := l.text(start.offset, l.c.offset)
+	switch id {
+	case "true":
+		l.setToken(TkBool, start.line, start.column, true)
+	case "false":
+		l.setToken(TkBool, start.line, start.column, false)
+	case "inf":
+		l.setToken(TkFloat, start.line, start.column, math.Inf(1))
+	case "nan":
+		l.setToken(TkFloat, start.line, start.column, math.NaN())
+	case "secret":
+		if l.c.r == '(' {
+			l.parseSecret(start)
+			return
+		}
+		l.setToken(TkIdentifier, start.line, start.column, id)
+	default:
+		l.setToken(TkIdentifier, start.line, start.column, id)
+	}
+}
+
+// parseSecret parses the reference of a secret: secret("scheme:ref").
+// Current rune is the opening parenthesis.
+func (l *Scanner) parseSecret(start char) {
+	if l.nextRune(); l.c.r != '"' {
+		l.setErrorToken("expected string after 'secret('")
+		l.Token.Hint = `secrets are written secret("scheme:reference")`
+		return
+	}
+	if l.parseString(); l.Token.Kind == TkError {
+		return
+	}
+	ref := l.Token.Value.(string)
+	if l.c.r != ')' {
+		l.setErrorToken("expected ')' after secret reference")
+		return
+	}
+	l.nextRune()
+	if ref == "" {
+		l.setErrorToken("empty secret reference")
+		l.Token.Line, l.Token.Column = start.line, start.column
+		return
+	}
+	l.setToken(TkSecret, start.line, start.column, ref)
+}
+
+func (l *Scanner) parseDate() bool {
+	start := l.c
+
+	// Dates start with 1 to 4 digits followed by '-'; check it before
+	// running the regexp on every number.
+	i := start.offset + 1
+	for i < start.offset+4 && unicode.IsDigit(rune(l.byteAt(i))) {
+		i++
+	}
+	if l.byteAt(i) != '-' {
+		return false
+	}
+	l.fill(start.offset + dateLen - l.offset)
+	end := start.offset + dateLen
+	if end > l.inputLen {
+		end = l.inputLen
+	}
+	// Regexp: easiest way to parse a date.
+	if d := dateRe.FindString(l.text(start.offset, end)); d != "" {
+		date, err := time.Parse(time.RFC3339, d)
+		if err != nil {
+			l.setErrorToken(err.Error())
+			return true
+		}
+		l.skipRune(len(d) - 1)
+		l.setToken(TkDate, start.line, start.column, date)
+		l.nextRune()
+		return true
+	}
+	return false
+}
+
+func (l *Scanner) parseNumber() {
+	k := TkInt
+	start := l.c
+	sign := ""
+	digits := "0123456789"
+	base := 10
+	separators := true // Underscores are correctly placed?
+
+	// STEP #1: parse a number and only handle the obvious syntax errors. Any
+	// problem will be catched up later by strconv anyway.
+	if l.c.r == '-' {
+		sign = "-"
+		l.nextRune()
+	} else if l.c.r == '+' {
+		sign = "+"
+		l.nextRune()
+	}
+
+	x := l.peekRune()
+	if sign != "" && unicode.IsLetter(l.c.r) {
+		// -inf, +inf, -nan and +nan.
+		l.parseSignedFloat(start, sign)
+		return
+	} else if l.c.r == '0' && strings.ContainsRune("xXoObB", x) {
+		l.nextRune() // Skips base prefix.
+		l.nextRune()
+		switch x {
+		case 'x', 'X':
+			digits = "0123456789abcdefABCDEF"
+			base = 16
+		case 'o', 'O':
+			digits = "01234567"
+			base = 8
+		default:
+			digits = "01"
+			base = 2
+		}
+		n := 0
+		if n, separators = l.acceptDigits(digits); n == 0 {
+			l.setErrorToken("malformed %s constant %q", baseNames[base],
+				l.text(start.offset, l.c.offset))
+			return
+		}
+		if unicode.IsLetter(l.c.r) || unicode.IsDigit(l.c.r) {
+			l.setErrorToken("invalid digit %q in %s constant", l.c.r,
+				baseNames[base])
+			return
+		}
+	} else {
+		digitsBeforeDot := true
+		n, ok := l.acceptDigits(digits)
+		separators = separators && ok
+		if n == 0 {
+			// .5 is a valid floating-point constant; we cannot flagged an
+			// error yet ('.' may be followed by digits).
+			if l.c.r != '.' {
+				l.setErrorToken("malformed constant %q",
+					l.text(start.offset, l.c.offset))
+				return
+			}
+			digitsBeforeDot = false
+		}
+		if l.acceptOneRune(".") {
+			k = TkFloat
+			n, ok := l.acceptDigits(digits)
+			separators = separators && ok
+			if n == 0 {
+				// 5. is a valid floating point constant. However, it's an
+				// error if there was no digits before the '.'.
+				if digitsBeforeDot == false {
+					l.setErrorToken("malformed floating-point constant %q",
+						l.text(start.offset, l.c.offset))
+					return
+				}
+			}
+		}
+		if l.acceptOneRune("eE") {
+			l.acceptOneRune("+-")
+			n, ok := l.acceptDigits(digits)
+			separators = separators && ok
+			if n == 0 {
+				l.setErrorToken("malformed floating-point constant exponent")
+				l.Token.Line, l.Token.Column = start.line, start.column
+				return
+			}
+		}
+	}
+	if k == TkInt && base == 10 || k == TkFloat {
+		// A letter right after a number starts a duration (1m30s) or a size
+		// (10MiB) unit.
+		if unicode.IsLetter(l.c.r) {
+			l.parseQuantity(start)
+			return
+		}
+	}
+	s := l.text(start.offset, l.c.offset)
+	if separators == false {
+		l.setErrorToken("'_' must separate successive digits in %q", s)
+		l.Token.Line, l.Token.Column = start.line, start.column
+		return
+	}
+	// STEP #2: use strconv to convert from string to int or float.
+	num := strings.Replace(s, "_", "", -1)
+	if k == TkInt {
+		if base != 10 {
+			// ParseInt() does not accept base prefixes when base is not 0.
+			// -0xFF must be converted to -FF.
+			num = sign + num[len(sign)+2:]
+		}
+		if ival, err := strconv.ParseInt(num, base, 64); err == nil {
+			l.setToken(k, start.line, start.column, ival)
+		} else if err.(*strconv.NumError).Err == strconv.ErrRange {
+			l.setErrorToken("integer constant %s overflows int64", s)
+			l.Token.Line, l.Token.Column = start.line, start.column
+		} else {
+			msg := err.Error()
+			msg = strings.TrimPrefix(msg, "strconv.ParseInt: parsing ")
+			l.setErrorToken(msg)
+		}
+	} else {
+		if fval, err := strconv.ParseFloat(num, 64); err == nil {
+			l.setToken(k, start.line, start.column, fval)
+		} else if err.(*strconv.NumError).Err == strconv.ErrRange {
+			l.setErrorToken("floating-point constant %s overflows float64", s)
+			l.Token.Line, l.Token.Column = start.line, start.column
+		} else {
+			msg := err.Error()
+			msg = strings.TrimPrefix(msg, "strconv.ParseFloat: parsing ")
+			l.setErrorToken(msg)
+		}
+	}
+}
+
+func (l *Scanner) parseSignedFloat(start char, sign string) {
+	for unicode.IsLetter(l.c.r) {
+		l.nextRune()
+	}
+	switch l.text(start.offset+len(sign), l.c.offset) {
+	case "inf":
+		if sign == "-" {
+			l.setToken(TkFloat, start.line, start.column, math.Inf(-1))
+		} else {
+			l.setToken(TkFloat, start.line, start.column, math.Inf(1))
+		}
+	case "nan":
+		l.setToken(TkFloat, start.line, start.column, math.NaN())
+	default:
+		l.setErrorToken("malformed constant %q",
+			l.text(start.offset, l.c.offset))
+		l.Token.Line, l.Token.Column = start.line, start.column
+	}
+}
+
+func (l *Scanner) parseQuantity(start char) {
+	for unicode.IsLetter(l.c.r) || unicode.IsDigit(l.c.r) || l.c.r == '.' {
+		l.nextRune()
+	}
+	s := l.text(start.offset, l.c.offset)
+	if m := sizeRe.FindStringSubmatch(s); m != nil {
+		for _, u := range sizeUnits {
+			// KB is accepted as an alias of kB.
+			if m[2] != u.name && (u.name != "kB" || m[2] != "KB") {
+				continue
+			}
+			// Sizes are scaled exactly; floating-point arithmetic would
+			// round 1.1kB to 1100.0000000000002 bytes.
+			r, _ := new(big.Rat).SetString(m[1])
+			r.Mul(r, new(big.Rat).SetInt64(u.factor))
+			switch {
+			case r.IsInt() == false:
+				l.setErrorToken("size constant %q is not a whole number of bytes", s)
+			case r.Num().IsInt64() == false:
+				l.setErrorToken("size constant %q overflows int64", s)
+			default:
+				l.setToken(TkSize, start.line, start.column, r.Num().Int64())
+				return
+			}
+			l.Token.Line, l.Token.Column = start.line, start.column
+			return
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		l.setErrorToken("malformed duration or size constant %q", s)
+		l.Token.Line, l.Token.Column = start.line, start.column
+		l.Token.Hint = "units are case sensitive (e.g. ms, h, MB, MiB)"
+		return
+	}
+	l.setToken(TkDuration, start.line, start.column, d)
+}
+
+func (l *Scanner) parseString() {
+	start := l.c
+
+	// Skips opening double quotes.
+	l.nextRune()
+	for {
+		if l.isEOLOrEOF() {
+			return
+		}
+		if l.c.r == '\\' {
+			// Saves position of '\'; if an error occurs this is where we
+			// should report the error.
+			es := l.c
+			l.nextRune()
+			switch l.c.r {
+			case 'b', 't', 'n', 'f', 'r', '"', '/', '\\':
+			case 'u':
+				for i := 0; i < 4; i++ {
+					l.nextRune()
+					if l.isEOLOrEOF() {
+						return
+					}
+					if !isHexadecimal(l.c.r) {
+						if l.c.r == '"' {
+							// End of string.
+							l.c.column = es.column
+							l.setErrorToken("malformed hex escape sequence %s",
+								l.text(es.offset, l.c.offset))
+							return
+						}
+						l.setErrorToken("non-hex character in escape sequence: %q",
+							l.c.r)
+						return
+					}
+				}
+			default:
+				l.setErrorToken("unknown escape sequence: %s",
+					l.text(es.offset, l.c.offset+utf8.RuneLen(l.c.r)))
+				l.Token.Hint = "valid escape sequences are \\b, \\t, \\n, \\f, \\r, \\\", \\/, \\\\ and \\uXXXX"
+				return
+			}
+		} else if l.c.r == '"' {
+			break
+		}
+		l.nextRune()
+	}
+	// Skips closing double quotes.
+	l.nextRune()
+
+	// Removes double quotes from string.
+	qlen := utf8.RuneLen('"')
+	s := l.text(start.offset+qlen, l.c.offset-qlen)
+	l.setToken(TkString, start.line, start.column, s)
+}
+
+// sourceLine returns given line of input, without end-of-line. Stream
+// lexers only know the line of the last token read; empty string is
+// returned for other lines.
+func (l *Scanner) SourceLine(line int) string {
+	if l.reader != nil {
+		return l.streamSourceLine(line)
+	}
+	s := l.contents
+	for ; line > 1; line-- {
+		i := strings.IndexByte(s, '\n')
+		if i == -1 {
+			return ""
+		}
+		s = s[i+1:]
+	}
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, "\r")
+}
+
+func (l *Scanner) streamSourceLine(line int) string {
+	for line == l.keepLine {
+		s := l.buf[l.keep-l.base:]
+		if i := bytes.IndexByte(s, '\n'); i != -1 {
+			return strings.TrimRight(string(s[:i]), "\r")
+		}
+		if l.err != nil || len(s) > maxSourceLine {
+			return strings.TrimRight(string(s), "\r")
+		}
+		// Reads rest of line.
+		l.fill(l.inputLen - l.offset + 1)
+	}
+	return ""
+}
+
+func (l *Scanner) isEOLOrEOF() bool {
+	if l.c.r == _EOF {
+		l.setErrorToken("end-of-file in string")
+		return true
+	}
+	if isEOL(l.c.r) {
+		l.setErrorToken("newline in string")
+		l.Token.Hint = "strings cannot span multiple lines; use \\n"
+		return true
+	}
+	return false
+}
+
+func (k Kind) String() string {
+	switch k {
+	case TkEOF:
+		return "eof"
+	case TkEOL:
+		return "eol"
+	case TkError:
+		return "error"
+	case TkIdentifier:
+		return "identifier"
+	case TkBool:
+		return "bool"
+	case TkString:
+		return "string"
+	case TkInt:
+		return "int64"
+	case TkFloat:
+		return "float64"
+	case TkDate:
+		return "time.Time"
+	case TkEqual:
+		return "equal"
+	case TkLBracket:
+		return "lbracket"
+	case TkRBracket:
+		return "rbracket"
+	case TkDuration:
+		return "time.Duration"
+	case TkSize:
+		return "config.Size"
+	case TkComment:
+		return "comment"
+	case TkSecret:
+		return "secret"
+	default:
+		return "comma"
+	}
+}
+
+func (c char) String() string {
+	pos := fmt.Sprintf("[%d - %3d:%3d]", c.offset, c.line, c.column)
+	return fmt.Sprintf("rune %s ('%c')", pos, c.r)
+}
+
+func (t Token) String() string {
+	pos := fmt.Sprintf("[%3d:%3d]", t.Line, t.Column)
+	// %-10s: IDENTIFIER is the biggest type name with 10 letters.
+	switch {
+	case t.Kind == TkEOF:
+		return fmt.Sprintf("%-10s %s", t.Kind, pos)
+	case t.Kind == TkEOL:
+		return fmt.Sprintf("%-10s %s", t.Kind, pos)
+	case t.Kind == TkError:
+		return fmt.Sprintf("%-10s %s \"%s\"", t.Kind, pos, t.Value.(string))
+	case t.Kind == TkIdentifier:
+		return fmt.Sprintf("%-10s %s \"%s\"", t.Kind, pos, t.Value.(string))
+	case t.Kind == TkBool:
+		return fmt.Sprintf("%-10s %s %t", t.Kind, pos, t.Value.(bool))
+	case t.Kind == TkString:
+		return fmt.Sprintf("%-10s %s \"%s\"", t.Kind, pos, t.Value.(string))
+	case t.Kind == TkInt:
+		return fmt.Sprintf("%-10s %s %d", t.Kind, pos, t.Value.(int64))
+	case t.Kind == TkFloat:
+		return fmt.Sprintf("%-10s %s %f)", t.Kind, pos, t.Value.(float64))
+	case t.Kind == TkDate:
+		date := (t.Value.(time.Time)).Format(time.RFC3339)
+		return fmt.Sprintf("%-10s %s %s", t.Kind, pos, date)
+	case t.Kind == TkEqual:
+		return fmt.Sprintf("%-10s %s '='", t.Kind, pos)
+	case t.Kind == TkLBracket:
+		return fmt.Sprintf("%-10s %s '['", t.Kind, pos)
+	case t.Kind == TkRBracket:
+		return fmt.Sprintf("%-10s %s ']'", t.Kind, pos)
+	case t.Kind == TkDuration:
+		return fmt.Sprintf("%-10s %s %s", t.Kind, pos, t.Value.(time.Duration))
+	case t.Kind == TkSize:
+		return fmt.Sprintf("%-10s %s %s", t.Kind, pos, FormatSize(t.Value.(int64)))
+	case t.Kind == TkComment:
+		return fmt.Sprintf("%-10s %s \"%s\"", t.Kind, pos, t.Value.(string))
+	case t.Kind == TkSecret:
+		return fmt.Sprintf("%-10s %s \"%s\"", t.Kind, pos, t.Value.(string))
+	default:
+		return fmt.Sprintf("%-10s %s ','", t.Kind, pos)
+	}
+}
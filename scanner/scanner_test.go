@@ -0,0 +1,824 @@
+package scanner_test
+
+import (
+	"fmt"
+	"github.com/cbonello/gp-config/scanner"
+	. "launchpad.net/gocheck"
+	"math"
+	"strings"
+	"testing/iotest"
+	"time"
+)
+
+type (
+	ScannerTests struct{}
+)
+
+var (
+	_ = Suite(&ScannerTests{})
+)
+
+func (st *ScannerTests) TestPass1(c *C) {
+	contents := `
+iden_ti-fier true false "abcd" "\u123456" 1234 +1 -210
+0xAb -0xFFee 5. 1.2 -2.3456 1.5E5 -1.4e-4
+# Comment
+1979-05-27T07:32:00Z = [ ] ,`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	c.Check(l.Token.Line, Equals, 1)
+	c.Check(l.Token.Column, Equals, 1)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 1)
+	c.Check(l.Token.Value, Equals, "iden_ti-fier")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkBool)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 14)
+	c.Check(l.Token.Value, Equals, true)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkBool)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 19)
+	c.Check(l.Token.Value, Equals, false)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkString)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 25)
+	c.Check(l.Token.Value, Equals, "abcd")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkString)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 32)
+	c.Check(l.Token.Value, Equals, "\\u123456")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 43)
+	c.Check(l.Token.Value, Equals, int64(1234))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 48)
+	c.Check(l.Token.Value, Equals, int64(1))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 51)
+	c.Check(l.Token.Value, Equals, int64(-210))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 55)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 1)
+	c.Check(l.Token.Value, Equals, int64(171))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 6)
+	c.Check(l.Token.Value, Equals, int64(-65518))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 14)
+	c.Check(l.Token.Value, Equals, float64(5.0))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 17)
+	c.Check(l.Token.Value, Equals, float64(1.2))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 21)
+	c.Check(l.Token.Value, Equals, float64(-2.3456))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 29)
+	c.Check(l.Token.Value, Equals, float64(1.5e5))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 35)
+	c.Check(l.Token.Value, Equals, float64(-1.4e-4))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, 42)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	c.Check(l.Token.Line, Equals, 4)
+	c.Check(l.Token.Column, Equals, 10)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDate)
+	c.Check(l.Token.Line, Equals, 5)
+	c.Check(l.Token.Column, Equals, 1)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEqual)
+	c.Check(l.Token.Line, Equals, 5)
+	c.Check(l.Token.Column, Equals, 22)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkLBracket)
+	c.Check(l.Token.Line, Equals, 5)
+	c.Check(l.Token.Column, Equals, 24)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkRBracket)
+	c.Check(l.Token.Line, Equals, 5)
+	c.Check(l.Token.Column, Equals, 26)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkComma)
+	c.Check(l.Token.Line, Equals, 5)
+	c.Check(l.Token.Column, Equals, 28)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+	c.Check(l.Token.Line, Equals, 5)
+	c.Check(l.Token.Column, Equals, 29)
+}
+
+// Invalid symbol.
+func (st *ScannerTests) TestSymbol1(c *C) {
+	contents := "("
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "unexpected '(' character")
+}
+
+// Non-print character.
+func (st *ScannerTests) TestSymbol2(c *C) {
+	contents := fmt.Sprintf("%c", 0x007)
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "unexpected \\u0007 character")
+}
+
+// Malformed date.
+func (st *ScannerTests) TestDate1(c *C) {
+	// 1975/05/27 99:32:00; hour is out of range.
+	contents := "1979-05-27T99:32:00Z"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals,
+		"parsing time \"1979-05-27T99:32:00Z\": hour out of range")
+}
+
+// Malformed constant.
+func (st *ScannerTests) TestNumber1(c *C) {
+	contents := "+"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed constant \"+\"")
+}
+
+// Malformed hexadecimal constant.
+func (st *ScannerTests) TestNumber2(c *C) {
+	contents := "0Xz"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed hex constant \"0X\"")
+}
+
+// Malformed floating-point number.
+func (st *ScannerTests) TestNumber3(c *C) {
+	contents := "123456789e123456789"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "\"123456789e123456789\": invalid syntax")
+}
+
+// Malformed floating-point contant.
+func (st *ScannerTests) TestNumber4(c *C) {
+	contents := "+."
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed floating-point constant \"+.\"")
+}
+
+// Malformed floating-point contant.
+func (st *ScannerTests) TestNumber5(c *C) {
+	contents := "+.1e"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed floating-point constant exponent")
+}
+
+// Malformed floating-point contant.
+func (st *ScannerTests) TestNumber6(c *C) {
+	contents := "+9.1e+"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed floating-point constant exponent")
+}
+
+// Out-of-range floating-point contant.
+func (st *ScannerTests) TestNumber7(c *C) {
+	contents := "0.123456789e123456789"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals,
+		"floating-point constant 0.123456789e123456789 overflows float64")
+}
+
+// Syntax error.
+func (st *ScannerTests) TestString1(c *C) {
+	contents := `"abcd`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "end-of-file in string")
+}
+
+// Syntax error.
+func (st *ScannerTests) TestString2(c *C) {
+	contents := `"a
+`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "newline in string")
+}
+
+// Malformed escape sequence.
+func (st *ScannerTests) TestString3(c *C) {
+	contents := `"a\ `
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "unknown escape sequence: \\ ")
+}
+
+// Malformed escape sequence.
+func (st *ScannerTests) TestString4(c *C) {
+	contents := `"a\u8`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "end-of-file in string")
+}
+
+// Malformed escape sequence.
+func (st *ScannerTests) TestString5(c *C) {
+	contents := `"a\u8A
+`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "newline in string")
+}
+
+// Malformed escape sequence.
+func (st *ScannerTests) TestString6(c *C) {
+	contents := `"a\u0ab"`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed hex escape sequence \\u0ab")
+}
+
+// Malformed escape sequence.
+func (st *ScannerTests) TestString7(c *C) {
+	contents := `"a\u0aby"`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "non-hex character in escape sequence: 'y'")
+}
+
+// String(): Dump function.
+func (st *ScannerTests) TestGetDump1(c *C) {
+	contents := `
+a true 1 2.3 2013-10-25T16:22:00Z "foo" = [ ] ,`
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	c.Check(l.Token.Line, Equals, 1)
+	c.Check(l.Token.Column, Equals, 1)
+	str := fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "eol        [  1:  1]")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 1)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "identifier [  2:  1] \"a\"")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkBool)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 3)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "bool       [  2:  3] true")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 8)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "int64      [  2:  8] 1")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 10)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "float64    [  2: 10] 2.300000)")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDate)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 14)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "time.Time  [  2: 14] 2013-10-25T16:22:00Z")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkString)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 35)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "string     [  2: 35] \"foo\"")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEqual)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 41)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "equal      [  2: 41] '='")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkLBracket)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 43)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "lbracket   [  2: 43] '['")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkRBracket)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 45)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "rbracket   [  2: 45] ']'")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkComma)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 47)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "comma      [  2: 47] ','")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 48)
+	str = fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "eof        [  2: 48]")
+}
+
+// Duration constants.
+func (st *ScannerTests) TestDuration1(c *C) {
+	contents := "1m30s 250ms -1.5h 10µs"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDuration)
+	c.Check(l.Token.Column, Equals, 1)
+	c.Check(l.Token.Value, Equals, 90*time.Second)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDuration)
+	c.Check(l.Token.Column, Equals, 7)
+	c.Check(l.Token.Value, Equals, 250*time.Millisecond)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDuration)
+	c.Check(l.Token.Column, Equals, 13)
+	c.Check(l.Token.Value, Equals, -90*time.Minute)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDuration)
+	c.Check(l.Token.Column, Equals, 19)
+	c.Check(l.Token.Value, Equals, 10*time.Microsecond)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+}
+
+// Size constants.
+func (st *ScannerTests) TestSize1(c *C) {
+	contents := "512B 64kB 64KB 10MiB 1.5GiB 2TB"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(512))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(64000))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(64000))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Column, Equals, 16)
+	c.Check(l.Token.Value, Equals, int64(10<<20))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(3<<29))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(2e12))
+	str := fmt.Sprintf("%s", l.Token)
+	c.Check(str, Equals, "config.Size [  1: 29] 2TB")
+}
+
+// Malformed duration or size constant.
+func (st *ScannerTests) TestQuantity1(c *C) {
+	contents := "x = 10mb"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	l.NextToken()
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Column, Equals, 5)
+	c.Check(l.Token.Value, Equals, "malformed duration or size constant \"10mb\"")
+}
+
+// Out-of-range size constant.
+func (st *ScannerTests) TestQuantity2(c *C) {
+	contents := "16384PiB"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "size constant \"16384PiB\" overflows int64")
+}
+
+// Size constants that are not a whole number of bytes.
+func (st *ScannerTests) TestQuantity3(c *C) {
+	contents := "1.5B 1.0001kB 1.1kB 0.5KiB"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Column, Equals, 1)
+	c.Check(l.Token.Value, Equals, "size constant \"1.5B\" is not a whole number of bytes")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Column, Equals, 6)
+	c.Check(l.Token.Value, Equals, "size constant \"1.0001kB\" is not a whole number of bytes")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(1100))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSize)
+	c.Check(l.Token.Value, Equals, int64(512))
+}
+
+// Octal, binary and hexadecimal constants.
+func (st *ScannerTests) TestNumber8(c *C) {
+	contents := "0o640 -0O17 0b1010 +0B1 -0xff 0640"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(0640))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(-017))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(10))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(1))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(-255))
+	l.NextToken()
+	// Leading zeros do not denote an octal constant.
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(640))
+}
+
+// Digit separators.
+func (st *ScannerTests) TestNumber9(c *C) {
+	contents := "1_000_000 0xFF_FF 1_000.000_1 1.5e1_0"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(1000000))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkInt)
+	c.Check(l.Token.Value, Equals, int64(0xFFFF))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Value, Equals, float64(1000.0001))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(l.Token.Value, Equals, float64(1.5e10))
+}
+
+// Misplaced digit separators.
+func (st *ScannerTests) TestNumber10(c *C) {
+	for _, contents := range []string{"1__0", "10_", "0x_1", "1_.5"} {
+		l := scanner.New("dummy.conf", contents)
+		l.NextToken()
+		c.Check(l.Token.Kind, Equals, scanner.TkError)
+		c.Check(l.Token.Column, Equals, 1)
+		c.Check(l.Token.Value, Equals,
+			fmt.Sprintf("'_' must separate successive digits in %q", contents))
+	}
+}
+
+// Invalid digits.
+func (st *ScannerTests) TestNumber11(c *C) {
+	contents := "0o78"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "invalid digit '8' in octal constant")
+
+	contents = "0b"
+	l = scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "malformed binary constant \"0b\"")
+}
+
+// Infinities and not-a-number.
+func (st *ScannerTests) TestNumber12(c *C) {
+	contents := "inf +inf -inf nan -nan -infinity"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(math.IsInf(l.Token.Value.(float64), 1), Equals, true)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(math.IsInf(l.Token.Value.(float64), 1), Equals, true)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(math.IsInf(l.Token.Value.(float64), -1), Equals, true)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(math.IsNaN(l.Token.Value.(float64)), Equals, true)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkFloat)
+	c.Check(math.IsNaN(l.Token.Value.(float64)), Equals, true)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Column, Equals, 24)
+	c.Check(l.Token.Value, Equals, "malformed constant \"-infinity\"")
+}
+
+// Out-of-range integer constant.
+func (st *ScannerTests) TestNumber13(c *C) {
+	contents := "0x1_0000_0000_0000_0000"
+
+	l := scanner.New("dummy.conf", contents)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals,
+		"integer constant 0x1_0000_0000_0000_0000 overflows int64")
+}
+
+// streamInput is a configuration exercising all tokens but errors.
+const streamInput = `
+iden_ti-fier true false "abcd" "\u123456" "caf\u00e9 \"x\"" 1234 +1 -210
+0xAb -0o17 0b101 1_000 5. 1.2 -2.3456 1.5E5 -1.4e-4 inf -inf
+# Comment
+1979-05-27T07:32:00Z 1m30s 10MiB 1.5kB = [ ] ,
+[ "example.com" ] é_identifier = "日本語"
+`
+
+// NewReaderLexer(): same tokens as string lexer. Input is larger than
+// lexer's buffer and is read one byte at a time.
+func (st *ScannerTests) TestStream1(c *C) {
+	contents := strings.Repeat(streamInput, 1000)
+
+	l1 := scanner.New("dummy.conf", contents)
+	l2 := scanner.NewReader("dummy.conf",
+		iotest.OneByteReader(strings.NewReader(contents)))
+	for {
+		l1.NextToken()
+		l2.NextToken()
+		c.Assert(l2.Token, DeepEquals, l1.Token)
+		if l1.Token.Kind == scanner.TkEOF {
+			break
+		}
+	}
+}
+
+// NewReaderLexer(): tokens and comments larger than lexer's buffer.
+func (st *ScannerTests) TestStream2(c *C) {
+	long := strings.Repeat("x", 300000)
+	contents := "# " + long + "\na = \"" + long + "\"\n" + long + " 1s"
+
+	l := scanner.NewReader("dummy.conf", strings.NewReader(contents))
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Value, Equals, "a")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEqual)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkString)
+	c.Check(l.Token.Value, Equals, long)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Value, Equals, long)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkDuration)
+	c.Check(l.Token.Line, Equals, 3)
+	c.Check(l.Token.Column, Equals, len(long)+2)
+	c.Check(l.Token.Value, Equals, time.Second)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+}
+
+// NewReaderLexer(): errors.
+func (st *ScannerTests) TestStream3(c *C) {
+	contents := "a = 'b'"
+
+	l := scanner.NewReader("dummy.conf",
+		iotest.OneByteReader(strings.NewReader(contents)))
+	l.NextToken()
+	l.NextToken()
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Column, Equals, 5)
+	c.Check(l.Token.Value, Equals, "unexpected ''' character")
+}
+
+// lexAll reads all tokens of given input and returns their count.
+func lexAll(contents string, stream bool) (n int) {
+	if stream {
+		l := scanner.NewReader("bench.conf", strings.NewReader(contents))
+		for l.NextToken(); l.Token.Kind != scanner.TkEOF; l.NextToken() {
+			n++
+		}
+	} else {
+		l := scanner.New("bench.conf", contents)
+		for l.NextToken(); l.Token.Kind != scanner.TkEOF; l.NextToken() {
+			n++
+		}
+	}
+	return n
+}
+
+// Throughput of string and stream lexers; run with -check.b -check.bmem to
+// also report allocations.
+func (st *ScannerTests) BenchmarkStringLexer(c *C) {
+	contents := strings.Repeat(streamInput, 5000)
+	c.SetBytes(int64(len(contents)))
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		lexAll(contents, false)
+	}
+}
+
+func (st *ScannerTests) BenchmarkStreamLexer(c *C) {
+	contents := strings.Repeat(streamInput, 5000)
+	c.SetBytes(int64(len(contents)))
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		lexAll(contents, true)
+	}
+}
+
+// Raw text and positions of tokens.
+func (st *ScannerTests) TestRaw1(c *C) {
+	contents := "\"a b\" = 0x1F, 1_000 10MiB # c\n"
+
+	l := scanner.New("dummy.conf", contents)
+	for _, raw := range []string{`"a b"`, "=", "0x1F", ",", "1_000", "10MiB", "\n", ""} {
+		l.NextToken()
+		c.Check(l.Token.Raw, Equals, raw)
+	}
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+
+	l = scanner.New("dummy.conf", "key = 1")
+	l.NextToken()
+	l.NextToken()
+	c.Check(l.Token.Pos(), Equals, scanner.Position{Line: 1, Column: 5})
+	c.Check(l.Token.End(), Equals, scanner.Position{Line: 1, Column: 6})
+	c.Check(l.Token.Pos().String(), Equals, "1:5")
+}
+
+// Comments are returned in ScanComments mode.
+func (st *ScannerTests) TestComment1(c *C) {
+	contents := "# header\na = 1 # trailing\n#"
+
+	l := scanner.New("dummy.conf", contents)
+	l.Mode = scanner.ScanComments
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkComment)
+	c.Check(l.Token.Value, Equals, " header")
+	c.Check(l.Token.Raw, Equals, "# header")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	l.NextToken()
+	l.NextToken()
+	l.NextToken()
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkComment)
+	c.Check(l.Token.Line, Equals, 2)
+	c.Check(l.Token.Column, Equals, 7)
+	c.Check(l.Token.Value, Equals, " trailing")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOL)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkComment)
+	c.Check(l.Token.Value, Equals, "")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+
+	// Comments are skipped by default, including at end of input.
+	l = scanner.New("dummy.conf", "a # comment")
+	l.NextToken()
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkEOF)
+}
+
+// IsIdentifier().
+func (st *ScannerTests) TestIsIdentifier1(c *C) {
+	c.Check(scanner.IsIdentifier("server"), Equals, true)
+	c.Check(scanner.IsIdentifier("_max-conns2"), Equals, true)
+	c.Check(scanner.IsIdentifier("été"), Equals, true)
+	c.Check(scanner.IsIdentifier(""), Equals, false)
+	c.Check(scanner.IsIdentifier("1st"), Equals, false)
+	c.Check(scanner.IsIdentifier("example.com"), Equals, false)
+	c.Check(scanner.IsIdentifier("database@dev"), Equals, true)
+	c.Check(scanner.IsIdentifier("database@"), Equals, false)
+	c.Check(scanner.IsIdentifier("a@b@c"), Equals, false)
+}
+
+// NextToken(): secret references.
+func (st *ScannerTests) TestSecret1(c *C) {
+	l := scanner.New("dummy.conf", `secret("env:DB_PASSWORD") secret = secret(db)`)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkSecret)
+	c.Check(l.Token.Value, Equals, "env:DB_PASSWORD")
+	c.Check(l.Token.Raw, Equals, `secret("env:DB_PASSWORD")`)
+	c.Check(l.Token.EndColumn, Equals, 26)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Value, Equals, "secret")
+	l.NextToken()
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "expected string after 'secret('")
+
+	l = scanner.New("dummy.conf", `secret("a:b"`)
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "expected ')' after secret reference")
+}
+
+// NextToken(): names of profile sections.
+func (st *ScannerTests) TestProfile1(c *C) {
+	l := scanner.New("dummy.conf", "database@dev a@1")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Value, Equals, "database@dev")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkIdentifier)
+	c.Check(l.Token.Value, Equals, "a")
+	l.NextToken()
+	c.Check(l.Token.Kind, Equals, scanner.TkError)
+	c.Check(l.Token.Value, Equals, "unexpected '@' character")
+}
+
+// FormatSize().
+func (st *ScannerTests) TestFormatSize1(c *C) {
+	c.Check(scanner.FormatSize(0), Equals, "0B")
+	c.Check(scanner.FormatSize(10<<20), Equals, "10MiB")
+	c.Check(scanner.FormatSize(64000), Equals, "64kB")
+	c.Check(scanner.FormatSize(1001), Equals, "1001B")
+}
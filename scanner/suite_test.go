@@ -0,0 +1,10 @@
+package scanner_test
+
+import (
+	. "launchpad.net/gocheck"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	TestingT(t)
+}
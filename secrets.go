@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type (
+	// SecretResolver resolves the references of secrets declared as
+	// secret("scheme:reference"); see RegisterSecretResolver.
+	SecretResolver interface {
+		// Resolve returns the secret identified by given reference, scheme
+		// excluded.
+		Resolve(ref string) (string, error)
+	}
+
+	// SecretResolverFunc adapts a function to the SecretResolver interface.
+	SecretResolverFunc func(ref string) (string, error)
+
+	// SecretRef is the reference of a secret, scheme included (e.g.
+	// "file:/run/secrets/db"). It stands for the value of secret options in
+	// String, Diff and exports so that secrets are never written out.
+	SecretRef string
+)
+
+// Registry of secret resolvers, keyed by lower-cased scheme.
+var secretResolvers = struct {
+	sync.RWMutex
+	byScheme map[string]SecretResolver
+}{byScheme: map[string]SecretResolver{}}
+
+func init() {
+	RegisterSecretResolver("file", SecretResolverFunc(resolveFileSecret))
+	RegisterSecretResolver("env", SecretResolverFunc(resolveEnvSecret))
+}
+
+// RegisterSecretResolver registers a resolver for the references of given
+// scheme, replacing any resolver previously registered for it. Resolvers
+// for schemes "file" (file:/run/secrets/db, the contents of the file without
+// trailing newline) and "env" (env:DB_PASSWORD, the value of an environment
+// variable) are built in:
+//
+//	config.RegisterSecretResolver("vault", config.SecretResolverFunc(func(ref string) (string, error) {
+//		return vaultClient.Read(ref)
+//	}))
+//
+// Secrets are resolved when configurations are loaded; resolvers must
+// therefore be registered beforehand.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolvers.Lock()
+	defer secretResolvers.Unlock()
+	secretResolvers.byScheme[strings.ToLower(scheme)] = r
+}
+
+// Resolve calls f(ref).
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// String returns the reference as written in configuration files:
+// secret("scheme:reference").
+func (r SecretRef) String() string {
+	return `secret("` + string(r) + `")`
+}
+
+// MarshalText implements encoding.TextMarshaler; secrets are marshaled as
+// by String.
+func (r SecretRef) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// SetResolveSecrets sets whether secrets are resolved when configurations are
+// loaded, which is the default. When they are not, the value of a secret
+// option is its reference as written in configuration files (e.g.
+// secret("env:DB_TOKEN")), encrypted values are kept encrypted, and
+// unresolvable secrets are not errors; tools
+// that read or edit configurations without using their secrets should so
+// disable resolution. Only configurations loaded afterwards are affected.
+func (c *Configuration) SetResolveSecrets(resolve bool) {
+	if c != nil {
+		c.Lock()
+		defer c.Unlock()
+		c.rawSecrets = resolve == false
+	}
+}
+
+// IsSecret returns true if given option holds a secret or an encrypted
+// value. Its value, returned by Get and GetString, should not be logged.
+// Options stay secret when overridden by Set, environment variables or flags
+// (see AddSource): String, Diff and exports still write the declared
+// reference or encrypted value. Only a plain declaration in a configuration
+// loaded afterwards makes them plain options again.
+func (c *Configuration) IsSecret(option string) bool {
+	if c != nil {
+		if opt := c.getOption(option); opt != nil {
+			return opt.masked != nil
+		}
+	}
+	return false
+}
+
+// secret returns the secret of given option identified by given reference,
+// or the reference itself if secrets are not resolved or if option will be
+// ignored (see resolves).
+func (c *Configuration) secret(option, ref string) (string, error) {
+	if c.resolves(option) == false {
+		return SecretRef(ref).String(), nil
+	}
+	return resolveSecret(ref)
+}
+
+// resolveSecret returns the secret identified by given reference.
+func resolveSecret(ref string) (string, error) {
+	i := strings.IndexByte(ref, ':')
+	if i <= 0 {
+		return "", fmt.Errorf("invalid secret reference %q; expected scheme:reference", ref)
+	}
+	secretResolvers.RLock()
+	r := secretResolvers.byScheme[strings.ToLower(ref[:i])]
+	secretResolvers.RUnlock()
+	if r == nil {
+		return "", fmt.Errorf("unknown secret scheme %q", ref[:i])
+	}
+	return r.Resolve(ref[i+1:])
+}
+
+func resolveFileSecret(filename string) (string, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(string(contents), "\n")
+	return strings.TrimSuffix(s, "\r"), nil
+}
+
+func resolveEnvSecret(name string) (string, error) {
+	if value, found := os.LookupEnv(name); found {
+		return value, nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", name)
+}
+
+// setSecret is similar to setOption for a secret string; masked stands for
+// its value in output.
+func (c *Configuration) setSecret(name, value string, masked interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	key := c.key(name)
+	c.sections[c.getSection(key)] = struct{}{}
+	v := c.setValue(key, reflect.ValueOf(value))
+	v.masked = masked
+	c.storeOption(key, canonicalPath(name), v)
+}
+
+// redacted returns the value of an option, or the value standing for it if
+// it holds a secret.
+func (v *configurationValue) redacted() interface{} {
+	if v.masked != nil {
+		return v.masked
+	}
+	return v.value
+}
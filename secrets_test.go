@@ -0,0 +1,158 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	. "launchpad.net/gocheck"
+	"os"
+	"strings"
+)
+
+type SecretsTests struct{}
+
+var (
+	_ = Suite(&SecretsTests{})
+)
+
+// Secrets are resolved when loaded and never written out.
+func (st *SecretsTests) TestSecret1(c *C) {
+	fn := writeConfig(c, "db_password", "s3cr3t\n")
+	os.Setenv("GPCONFIG_TEST_TOKEN", "t0k3n")
+	defer os.Unsetenv("GPCONFIG_TEST_TOKEN")
+	contents := fmt.Sprintf(`[database]
+user = "admin"
+password = secret("file:%s")
+token = secret("ENV:GPCONFIG_TEST_TOKEN")
+`, fn)
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(contents), IsNil)
+	c.Check(cfg.GetStringDefault("database.password", ""), Equals, "s3cr3t")
+	c.Check(cfg.GetStringDefault("database.token", ""), Equals, "t0k3n")
+	c.Check(cfg.IsSecret("database.password"), Equals, true)
+	c.Check(cfg.IsSecret("database.user"), Equals, false)
+	c.Check(cfg.String(), Equals, `database.user = "admin"
+database.password = secret("file:`+fn+`")
+database.token = secret("ENV:GPCONFIG_TEST_TOKEN")
+`)
+
+	for _, format := range config.Formats() {
+		if format == "kv" {
+			// Registered by FormatsTests.
+			continue
+		}
+		var buf bytes.Buffer
+		c.Assert(cfg.Export(&buf, format), IsNil)
+		c.Check(strings.Contains(buf.String(), "s3cr3t"), Equals, false, Commentf(format))
+		c.Check(strings.Contains(buf.String(), "t0k3n"), Equals, false, Commentf(format))
+		if config.LookupFormat(format).Name() == "yaml" || format == "env" {
+			continue
+		}
+		// References are read back.
+		dst := config.NewConfiguration()
+		c.Assert(dst.LoadFormat(format, "app", &buf), IsNil)
+		c.Check(config.Diff(cfg, dst), HasLen, 0, Commentf(format))
+		c.Check(dst.GetStringDefault("database.password", ""), Equals, "s3cr3t")
+	}
+
+	dst := config.NewConfiguration()
+	c.Assert(dst.LoadString(`[database]
+password = "s3cr3t"
+token = secret("env:GPCONFIG_TEST_TOKEN")`), IsNil)
+	changes := config.Diff(cfg, dst)
+	c.Assert(changes, HasLen, 3)
+	c.Check(changes[0].String(), Equals, `~ database.password = secret("file:`+fn+`") -> "s3cr3t"`)
+	c.Check(changes[1].String(), Equals, `~ database.token = secret("ENV:GPCONFIG_TEST_TOKEN") -> secret("env:GPCONFIG_TEST_TOKEN")`)
+	c.Check(changes[2].Old, Equals, "admin")
+}
+
+// Errors, RegisterSecretResolver().
+func (st *SecretsTests) TestSecret2(c *C) {
+	tests := []struct {
+		contents string
+		msg      string
+		line     int
+		column   int
+		code     config.ErrorCode
+	}{
+		{"a = 1\nb = secret(\"kms:db\")", `'b': unknown secret scheme "kms"`, 2, 5, config.ErrSecret},
+		{"a = secret(\"db\")", `'a': invalid secret reference "db"; expected scheme:reference`, 1, 5, config.ErrSecret},
+		{"a = secret(\"env:GPCONFIG_TEST_UNSET\")", "'a': environment variable GPCONFIG_TEST_UNSET is not set", 1, 5, config.ErrSecret},
+		{"a = [\"x\", secret(\"env:HOME\")]", "'a': secrets cannot be array elements", 1, 11, config.ErrInvalidValue},
+		{"a = secret(\"\")", "'a': empty secret reference", 1, 5, config.ErrSyntax},
+		{"a = secret(db)", "'a': expected string after 'secret('", 1, 12, config.ErrSyntax},
+	}
+	for _, test := range tests {
+		cfg := config.NewConfiguration()
+		var err *config.ConfigurationError
+		c.Assert(errors.As(cfg.LoadString(test.contents), &err), Equals, true, Commentf(test.contents))
+		c.Check(err.Error(), Equals, test.msg, Commentf(test.contents))
+		c.Check(err.Line, Equals, test.line, Commentf(test.contents))
+		c.Check(err.Column, Equals, test.column, Commentf(test.contents))
+		c.Check(err.Code, Equals, test.code, Commentf(test.contents))
+	}
+
+	var err error = config.NewConfiguration().LoadString(`a = secret("file:missing.txt")`)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+	err = config.NewConfiguration().LoadJSON("app.json", strings.NewReader(`{"a": "secret(\"kms:db\")"}`))
+	c.Check(errors.Is(err, config.ErrSecret), Equals, true)
+
+	config.RegisterSecretResolver("Vault", config.SecretResolverFunc(func(ref string) (string, error) {
+		return "v:" + ref, nil
+	}))
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadINI("app.ini", strings.NewReader(`password = secret("vault:db/pass")`)), IsNil)
+	c.Check(cfg.GetStringDefault("password", ""), Equals, "v:db/pass")
+}
+
+// SetResolveSecrets().
+func (st *SecretsTests) TestSecret3(c *C) {
+	contents := "[database]\nport = 5432\npassword = secret(\"env:GPCONFIG_TEST_UNSET\")\n"
+	cfg := config.NewConfiguration()
+	c.Check(cfg.LoadString(contents), NotNil)
+
+	cfg.SetResolveSecrets(false)
+	c.Assert(cfg.LoadString(contents), IsNil)
+	c.Assert(cfg.LoadJSON("app.json", strings.NewReader(`{"token": "secret(\"kms:db\")"}`)), IsNil)
+	c.Check(cfg.GetIntDefault("database.port", 0), Equals, int64(5432))
+	c.Check(cfg.GetStringDefault("database.password", ""), Equals, `secret("env:GPCONFIG_TEST_UNSET")`)
+	c.Check(cfg.IsSecret("database.password"), Equals, true)
+	c.Check(cfg.String(), Equals, `database.port = 5432
+database.password = secret("env:GPCONFIG_TEST_UNSET")
+token = secret("kms:db")
+`)
+}
+
+// Secrets overridden by Set() and environment variables stay secret.
+func (st *SecretsTests) TestSecret4(c *C) {
+	os.Setenv("GPCONFIG_TEST_TOKEN", "t0k3n")
+	defer os.Unsetenv("GPCONFIG_TEST_TOKEN")
+	os.Setenv("APP_DATABASE_TOKEN", "0v3rr1d3")
+	defer os.Unsetenv("APP_DATABASE_TOKEN")
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString(`[database]
+user = "admin"
+token = secret("env:GPCONFIG_TEST_TOKEN")`), IsNil)
+	c.Assert(cfg.AddSource(config.NewEnvSource("app")), IsNil)
+	c.Check(cfg.GetStringDefault("database.token", ""), Equals, "0v3rr1d3")
+	c.Check(cfg.IsSecret("database.token"), Equals, true)
+	c.Check(cfg.String(), Equals, `database.user = "admin"
+database.token = secret("env:GPCONFIG_TEST_TOKEN")
+`)
+	for _, format := range []string{"cfg", "json", "yaml", "env"} {
+		var buf bytes.Buffer
+		c.Assert(cfg.Export(&buf, format), IsNil)
+		c.Check(strings.Contains(buf.String(), "0v3rr1d3"), Equals, false, Commentf(format))
+	}
+
+	c.Assert(cfg.Set("database.token", "s3cr3t"), IsNil)
+	c.Check(cfg.IsSecret("database.token"), Equals, true)
+	changes := config.Diff(config.NewConfiguration(), cfg)
+	c.Assert(changes, HasLen, 2)
+	c.Check(changes[0].String(), Equals, `+ database.token = secret("env:GPCONFIG_TEST_TOKEN")`)
+
+	// Plain declarations loaded afterwards are not secret.
+	c.Assert(cfg.LoadString("[database]\ntoken = \"plain\""), IsNil)
+	c.Check(cfg.IsSecret("database.token"), Equals, false)
+}
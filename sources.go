@@ -0,0 +1,426 @@
+package config
+
+import (
+	"flag"
+	"github.com/cbonello/gp-config/scanner"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Source supplies options to a configuration; see AddSource. Sources
+	// are loaded in order of precedence, each one overriding the options
+	// supplied by the previous ones.
+	Source interface {
+		// Name identifies the source in errors (e.g. a filename).
+		Name() string
+		// Load loads the options of the source into c, which holds the
+		// options supplied by sources of lower precedence.
+		Load(c *Configuration) error
+		// Watch returns a channel receiving a value whenever the source
+		// changes, or nil if the source cannot be watched. The channel is
+		// closed once stop is closed.
+		Watch(stop <-chan struct{}) <-chan struct{}
+	}
+
+	// FileSource is a source reading a configuration file.
+	FileSource struct {
+		filename string
+		opts     *LoadOptions
+		// PollInterval is the interval at which the modification time of
+		// the file is checked by Watch; zero disables watching.
+		PollInterval time.Duration
+	}
+
+	stringSource struct {
+		name, contents string
+	}
+
+	envSource struct {
+		prefix string
+	}
+
+	flagSource struct {
+		flags  *flag.FlagSet
+		prefix string
+	}
+)
+
+// AddSource loads given source and adds it to the sources of the
+// configuration, with a precedence higher than the ones already added:
+//
+//	cfg.AddSource(config.NewFileSource("defaults.cfg", nil))
+//	cfg.AddSource(config.NewFileSource("app.cfg", &config.LoadOptions{Optional: true}))
+//	cfg.AddSource(config.NewEnvSource("app"))
+//	cfg.AddSource(config.NewFlagSource(flag.CommandLine, ""))
+//
+// Options loaded before the first call to AddSource have the lowest
+// precedence. Nothing is changed if the source cannot be loaded.
+func (c *Configuration) AddSource(s Source) error {
+	c.reload.Lock()
+	defer c.reload.Unlock()
+	layer := c.clone()
+	if err := s.Load(layer); err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.base == nil {
+		c.base = c.cloneLocked()
+	}
+	c.sources = append(c.sources, s)
+	c.sections, c.options, c.seq = layer.sections, layer.options, layer.seq
+	return nil
+}
+
+// Reload loads all sources again and replaces the options of the
+// configuration with theirs. Options loaded by other means than AddSource
+// after the first call to AddSource are discarded. Nothing is changed if a
+// source cannot be loaded.
+func (c *Configuration) Reload() error {
+	c.reload.Lock()
+	defer c.reload.Unlock()
+	c.RLock()
+	base, sources := c.base, c.sources
+	c.RUnlock()
+	if base == nil {
+		return nil
+	}
+	layer := base.clone()
+	for _, s := range sources {
+		if err := s.Load(layer); err != nil {
+			return err
+		}
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.sections, c.options, c.seq = layer.sections, layer.options, layer.seq
+	return nil
+}
+
+// Watch reloads the configuration whenever one of its sources reports a
+// change, until stop is closed. Sources added afterwards are not watched.
+// If not nil, fn is called with the result of each reload.
+func (c *Configuration) Watch(stop <-chan struct{}, fn func(err error)) {
+	c.RLock()
+	sources := c.sources
+	c.RUnlock()
+
+	changed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	for _, s := range sources {
+		ch := s.Watch(stop)
+		if ch == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range ch {
+				select {
+				case changed <- struct{}{}:
+				default:
+					// A reload is already pending.
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(changed)
+	}()
+	go func() {
+		for range changed {
+			err := c.Reload()
+			if fn != nil {
+				fn(err)
+			}
+		}
+	}()
+}
+
+// clone returns a copy of the configuration, without its sources.
+func (c *Configuration) clone() *Configuration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.cloneLocked()
+}
+
+// cloneLocked is similar to clone. Caller must hold the lock.
+func (c *Configuration) cloneLocked() *Configuration {
+	dst := c.blankLocked()
+	dst.seq = c.seq
+	for k := range c.sections {
+		dst.sections[k] = struct{}{}
+	}
+	for k, v := range c.options {
+		dst.options[k] = v
+	}
+	return dst
+}
+
+// blankLocked returns an empty configuration with the settings of c: case
+// sensitivity, key provider, profile and resolution of secrets. Caller must
+// hold the lock.
+func (c *Configuration) blankLocked() *Configuration {
+	dst := NewConfiguration()
+	dst.caseSensitive, dst.keys, dst.rawSecrets = c.caseSensitive, c.keys, c.rawSecrets
+	dst.profile = c.profile
+	return dst
+}
+
+// NewFileSource returns a source loading given file according to opts, as
+// Load does.
+func NewFileSource(filename string, opts *LoadOptions) *FileSource {
+	return &FileSource{filename: filename, opts: opts}
+}
+
+// Name returns the filename.
+func (s *FileSource) Name() string {
+	return s.filename
+}
+
+// Load loads the file.
+func (s *FileSource) Load(c *Configuration) error {
+	_, err := c.Load(s.filename, s.opts)
+	return err
+}
+
+// Watch polls the modification time of the file every PollInterval. A
+// change is reported when the file is modified, created or removed.
+func (s *FileSource) Watch(stop <-chan struct{}) <-chan struct{} {
+	if s.PollInterval <= 0 {
+		return nil
+	}
+	modTime := func() time.Time {
+		if fi, err := os.Stat(s.filename); err == nil {
+			return fi.ModTime()
+		}
+		return time.Time{}
+	}
+	ch := make(chan struct{})
+	last := modTime()
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(s.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if t := modTime(); t.Equal(last) == false {
+					last = t
+					select {
+					case ch <- struct{}{}:
+					case <-stop:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// NewStringSource returns a source loading a configuration in native format
+// from given string. Name identifies the configuration in errors.
+func NewStringSource(name, contents string) Source {
+	return &stringSource{name, contents}
+}
+
+func (s *stringSource) Name() string {
+	return s.name
+}
+
+func (s *stringSource) Load(c *Configuration) error {
+	return c.LoadReader(s.name, strings.NewReader(s.contents))
+}
+
+func (s *stringSource) Watch(stop <-chan struct{}) <-chan struct{} {
+	return nil
+}
+
+// NewEnvSource returns a source overriding options with environment
+// variables named as by ExportEnv: with prefix "app", APP_SERVER_PORT
+// overrides option server.port. Only options supplied by sources of lower
+// precedence are overridden. Values are read according to the type of the
+// option (see ParseValue).
+func NewEnvSource(prefix string) Source {
+	return &envSource{prefix}
+}
+
+func (s *envSource) Name() string {
+	if s.prefix == "" {
+		return "environment"
+	}
+	return "environment (" + EnvKey(s.prefix) + "_*)"
+}
+
+func (s *envSource) Load(c *Configuration) error {
+	tree, err := c.tree()
+	if err != nil {
+		return err
+	}
+	for _, section := range tree {
+		for _, o := range section.options {
+			value, found := os.LookupEnv(EnvKey(s.prefix, section.name, o.name))
+			if found == false {
+				continue
+			}
+			path := JoinPath(o.name)
+			if section.name != "" {
+				path = JoinPath(section.name, o.name)
+			}
+			if err := c.setString(path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *envSource) Watch(stop <-chan struct{}) <-chan struct{} {
+	return nil
+}
+
+// NewFlagSource returns a source setting options with the flags of given
+// set whose name is made of prefix and an option path: with prefix
+// "config.", flag -config.server.port sets option server.port. Only flags
+// set on the command line are used; the set must be parsed before the
+// source is loaded. Values of options supplied by sources of lower
+// precedence are read according to their type (see ParseValue); other
+// options have the type of the flag.
+func NewFlagSource(flags *flag.FlagSet, prefix string) Source {
+	return &flagSource{flags, prefix}
+}
+
+func (s *flagSource) Name() string {
+	return "command line"
+}
+
+func (s *flagSource) Load(c *Configuration) (err error) {
+	s.flags.Visit(func(f *flag.Flag) {
+		if err != nil || strings.HasPrefix(f.Name, s.prefix) == false {
+			return
+		}
+		path := strings.TrimPrefix(f.Name, s.prefix)
+		if _, perr := SplitPath(path); perr != nil || path == "" {
+			return
+		}
+		if c.HasOption(path) {
+			err = c.setString(path, f.Value.String())
+			return
+		}
+		var value interface{} = f.Value.String()
+		if g, ok := f.Value.(flag.Getter); ok {
+			switch v := g.Get().(type) {
+			case bool, float64, time.Duration:
+				value = v
+			case int:
+				value = int64(v)
+			case int64:
+				value = v
+			case uint:
+				value = int64(v)
+			case uint64:
+				value = int64(v)
+			}
+		}
+		c.setOption(path, value)
+	})
+	return err
+}
+
+func (s *flagSource) Watch(stop <-chan struct{}) <-chan struct{} {
+	return nil
+}
+
+// setString sets an existing option to the value read from s according to
+// its type.
+func (c *Configuration) setString(path, s string) error {
+	v := c.getOption(path)
+	value, err := ParseValue(path, s, v.value)
+	if err != nil {
+		return err
+	}
+	c.overrideOption(path, value)
+	return nil
+}
+
+// ParseValue reads the value of an option from a string, according to the
+// type of given value (bool, int64, float64, time.Time, string,
+// time.Duration, Size or a slice of one of those types): "true", "80",
+// "2.5", "1979-05-27T07:32:00Z", "1m30s", "10MiB", ... Strings are used as
+// is and array elements are separated by commas. Path is used in errors,
+// which have code ErrTypeMismatch.
+//
+//	port, err := config.ParseValue("server.port", "8080", int64(0))
+func ParseValue(path, s string, value interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != sliceType {
+		return parseScalar(path, s, kindOf(value))
+	}
+	elements := strings.Split(s, ",")
+	values := reflect.MakeSlice(rv.Type(), len(elements), len(elements))
+	kind := kindOf(reflect.Zero(rv.Type().Elem()).Interface())
+	for i, e := range elements {
+		v, err := parseScalar(path, strings.TrimSpace(e), kind)
+		if err != nil {
+			return nil, err
+		}
+		values.Index(i).Set(reflect.ValueOf(v))
+	}
+	return values.Interface(), nil
+}
+
+func parseScalar(path, s string, kind scanner.Kind) (interface{}, error) {
+	if kind == TkString {
+		return s, nil
+	}
+	kinds := []scanner.Kind{kind}
+	if kind == TkFloat {
+		kinds = append(kinds, TkInt)
+	}
+	v, ok := literalValue(s, kinds...)
+	if ok == false {
+		return nil, newOptionError(ErrTypeMismatch, path, "invalid %s value %q", kind, s)
+	}
+	if i, isInt := v.(int64); isInt && kind == TkFloat {
+		v = float64(i)
+	}
+	return v, nil
+}
+
+// Set sets an option, declaring it if needed. Value must be a bool, an
+// int64, a float64, a time.Time, a string, a time.Duration, a Size or a
+// non-empty slice of one of those types; ints are converted to int64. Strings
+// are stored as is, as when they are read from a configuration file. An
+// option holding a secret stays one (see IsSecret).
+func (c *Configuration) Set(option string, value interface{}) error {
+	if names, err := SplitPath(option); err != nil || len(names) > 2 {
+		return newOptionError(ErrInvalidArgument, option, "invalid option path")
+	}
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case []int:
+		a := make([]int64, len(v))
+		for i := range v {
+			a[i] = int64(v[i])
+		}
+		value = a
+	case bool, int64, float64, time.Time, string, time.Duration, Size:
+	case []bool, []int64, []float64, []time.Time, []string, []time.Duration, []Size:
+		if reflect.ValueOf(v).Len() == 0 {
+			return newOptionError(ErrInvalidValue, option, "empty arrays are not supported")
+		}
+	default:
+		return newOptionError(ErrInvalidValue, option, "unsupported type %T", value)
+	}
+	c.overrideOption(option, value)
+	return nil
+}
@@ -0,0 +1,216 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cbonello/gp-config"
+	"io/ioutil"
+	. "launchpad.net/gocheck"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	SourcesTests struct{}
+
+	// fakeSource is an in-process key-value store.
+	fakeSource struct {
+		sync.Mutex
+		values  map[string]string
+		err     error
+		changed chan struct{}
+	}
+)
+
+var (
+	_ = Suite(&SourcesTests{})
+)
+
+func newFakeSource(values map[string]string) *fakeSource {
+	return &fakeSource{values: values, changed: make(chan struct{})}
+}
+
+func (s *fakeSource) Name() string {
+	return "fake"
+}
+
+func (s *fakeSource) Load(c *config.Configuration) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	for path, value := range s.values {
+		if err := c.Set(path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeSource) Watch(stop <-chan struct{}) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-s.changed:
+				ch <- struct{}{}
+			}
+		}
+	}()
+	return ch
+}
+
+func (s *fakeSource) set(path, value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.values[path] = value
+}
+
+// AddSource(): sources are applied by increasing precedence.
+func (st *SourcesTests) TestAddSource1(c *C) {
+	fn := writeConfig(c, "app.json", `{"server": {"port": 8080, "timeout": "10s"}}`)
+	os.Setenv("GPCONFIG_TEST_SERVER_TIMEOUT", "1m30s")
+	os.Setenv("GPCONFIG_TEST_SERVER_UNKNOWN", "1")
+	defer os.Unsetenv("GPCONFIG_TEST_SERVER_TIMEOUT")
+	defer os.Unsetenv("GPCONFIG_TEST_SERVER_UNKNOWN")
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags.Bool("v", false, "verbose")
+	flags.String("config.server.host", "localhost", "host")
+	flags.Int("config.server.workers", 4, "workers")
+	flags.Int("config.server.port", 80, "port")
+	c.Assert(flags.Parse([]string{"-v", "-config.server.workers=8", "-config.server.port", "9090"}), IsNil)
+
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.AddSource(config.NewStringSource("defaults", "[server]\nhost = \"localhost\"\nport = 80")), IsNil)
+	c.Assert(cfg.AddSource(config.NewFileSource(fn, nil)), IsNil)
+	c.Assert(cfg.AddSource(config.NewEnvSource("gpconfig_test")), IsNil)
+	c.Assert(cfg.AddSource(config.NewFlagSource(flags, "config.")), IsNil)
+	c.Check(cfg.String(), Equals, `server.host = "localhost"
+server.port = 9090
+server.timeout = 1m30s
+server.workers = 8
+`)
+}
+
+// AddSource(), Reload(): nothing is changed on error.
+func (st *SourcesTests) TestAddSource2(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString("a = 1"), IsNil)
+	src := newFakeSource(map[string]string{"b": "x"})
+	c.Assert(cfg.AddSource(src), IsNil)
+
+	err := cfg.AddSource(config.NewStringSource("bad", "c = "))
+	c.Check(errors.Is(err, config.ErrSyntax), Equals, true)
+	err = cfg.AddSource(config.NewFileSource("missing.cfg", nil))
+	c.Check(errors.Is(err, config.ErrNotFound), Equals, true)
+	c.Assert(cfg.AddSource(config.NewFileSource("missing.cfg", &config.LoadOptions{Optional: true})), IsNil)
+
+	os.Setenv("GPCONFIG_TEST_A", "one")
+	defer os.Unsetenv("GPCONFIG_TEST_A")
+	err = cfg.AddSource(config.NewEnvSource("gpconfig_test"))
+	c.Check(errors.Is(err, config.ErrTypeMismatch), Equals, true)
+	c.Check(err, ErrorMatches, `'a': invalid int64 value "one"`)
+
+	src.err = fmt.Errorf("connection refused")
+	c.Check(cfg.Reload(), ErrorMatches, "connection refused")
+	c.Check(cfg.String(), Equals, "a = 1\nb = \"x\"\n")
+}
+
+// Reload(), Watch(): fake source.
+func (st *SourcesTests) TestWatch1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.LoadString("a = 1"), IsNil)
+	src := newFakeSource(map[string]string{"b": "x"})
+	c.Assert(cfg.AddSource(src), IsNil)
+	// Options loaded after the first source are discarded by Reload.
+	c.Assert(cfg.LoadString("c = 2"), IsNil)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	reloaded := make(chan error)
+	cfg.Watch(stop, func(err error) { reloaded <- err })
+
+	src.set("b", "y")
+	src.changed <- struct{}{}
+	select {
+	case err := <-reloaded:
+		c.Check(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("configuration not reloaded")
+	}
+	c.Check(cfg.String(), Equals, "a = 1\nb = \"y\"\n")
+}
+
+// FileSource.Watch().
+func (st *SourcesTests) TestWatch2(c *C) {
+	fn := writeConfig(c, "app.cfg", "a = 1")
+	src := config.NewFileSource(fn, nil)
+	c.Check(src.Watch(nil), IsNil)
+	src.PollInterval = 10 * time.Millisecond
+	cfg := config.NewConfiguration()
+	c.Assert(cfg.AddSource(src), IsNil)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	reloaded := make(chan error, 1)
+	cfg.Watch(stop, func(err error) { reloaded <- err })
+
+	c.Assert(ioutil.WriteFile(fn, []byte("a = 2"), 0644), IsNil)
+	later := time.Now().Add(time.Hour)
+	c.Assert(os.Chtimes(fn, later, later), IsNil)
+	select {
+	case err := <-reloaded:
+		c.Check(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("configuration not reloaded")
+	}
+	c.Check(cfg.GetIntDefault("a", 0), Equals, int64(2))
+}
+
+// ParseValue().
+func (st *SourcesTests) TestParseValue1(c *C) {
+	tests := []struct {
+		s, template, expected interface{}
+	}{
+		{"true", false, true},
+		{"8080", int64(0), int64(8080)},
+		{"2", 0.0, 2.0},
+		{"1979-05-27T07:32:00Z", time.Time{}, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)},
+		{"a, b", "", "a, b"},
+		{"1m30s", time.Duration(0), 90 * time.Second},
+		{"10MiB", config.Size(0), config.Size(10 << 20)},
+		{"1, 2", []int64{}, []int64{1, 2}},
+		{"a,b", []string{}, []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		v, err := config.ParseValue("a", test.s.(string), test.template)
+		c.Check(err, IsNil)
+		c.Check(v, DeepEquals, test.expected)
+	}
+	_, err := config.ParseValue("a", "1, x", []int64{})
+	c.Check(errors.Is(err, config.ErrTypeMismatch), Equals, true)
+	c.Check(err, ErrorMatches, `'a': invalid int64 value "x"`)
+}
+
+// Set().
+func (st *SourcesTests) TestSet1(c *C) {
+	cfg := config.NewConfiguration()
+	c.Check(cfg.Set("server.port", 80), IsNil)
+	c.Check(cfg.Set(`"example.com".ratios`, []float64{1.5}), IsNil)
+	c.Check(cfg.Set("server.port", int64(8080)), IsNil)
+	c.Check(cfg.String(), Equals, "server.port = 8080\n\"example.com\".ratios = [1.500000]\n")
+
+	err := cfg.Set("a", uint8(1))
+	c.Check(errors.Is(err, config.ErrInvalidValue), Equals, true)
+	c.Check(err, ErrorMatches, "'a': unsupported type uint8")
+	err = cfg.Set("a", []string{})
+	c.Check(errors.Is(err, config.ErrInvalidValue), Equals, true)
+	err = cfg.Set("a.b.c", 1)
+	c.Check(errors.Is(err, config.ErrInvalidArgument), Equals, true)
+}